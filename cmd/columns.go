@@ -0,0 +1,301 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/columns"
+	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/metrics"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+	"github.com/sam0392in/kshow/pkg/kshow"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Prefix of -o selecting custom columns
+const customColumns = "custom-columns="
+
+// Built-in column presets per kind, default is used without flags and detailed with --detailed
+var columnPresets = map[string]map[string]string{
+	"pods": {
+		"default":  "POD:name,READY:ready,STATUS:status,RESTART:restarts,AGE:age,NAMESPACE:namespace",
+		"detailed": "POD:name,AGE:age,STATUS:status,NAMESPACE:namespace,NODE:node,TENANCY:tenancy",
+		"usage":    "POD:name,NAMESPACE:namespace,NODE:node,TENANCY:tenancy,REQ-CPU:cpu.request,CPU:cpu.current,REQ-MEM:mem.request,MEMORY:mem.current",
+	},
+	"deployments": {
+		"default":  "DEPLOYMENT:name,NAMESPACE:namespace,REPLICAS:replicas",
+		"detailed": "DEPLOYMENT:name,NAMESPACE:namespace,READY:ready,DISTRIBUTION:distribution,MIN:hpa.min,MAX:hpa.max,CURRENT:hpa.current,DESIRED:hpa.desired,METRICS:hpa.metrics,PDB:pdb,TOLERATIONS:tolerations",
+		"usage":    "DEPLOYMENT:name,NAMESPACE:namespace,READY:ready,DISTRIBUTION:distribution,CPU:cpu.current,MEMORY:mem.current",
+	},
+	"nodes": {
+		"default":  "NODE:name,STATUS:status,AGE:age,VERSION:version",
+		"detailed": "NODE:name,STATUS:status,AGE:age,NODEGROUP:node.group,TENANCY:tenancy,INSTANCE-TYPE:node.instance-type,ARCH:node.arch,AWS-ZONE:node.zone",
+		"usage":    "NODE:name,NODEGROUP:node.group,TENANCY:tenancy,ALLOC-CPU:cpu.allocatable,CPU:cpu.current,ALLOC-MEM:mem.allocatable,MEMORY:mem.current",
+	},
+}
+
+/*
+Get the columns of a kind from -o custom-columns=..., or the preset named by --columns,
+--detailed or default. Presets of the config file override the built-in ones
+*/
+func getColumns(kind string) ([]columns.Column, error) {
+	if strings.HasPrefix(*output, customColumns) {
+		return columns.Parse(strings.TrimPrefix(*output, customColumns))
+	}
+	if *output != "" {
+		return nil, errors.New("unknown output format " + *output + ", use custom-columns=HEADER:field,...")
+	}
+	name := "default"
+	if *detailed {
+		name = "detailed"
+	}
+	if *columnsPreset != "" {
+		name = *columnsPreset
+	}
+	if spec, ok := cfg.ColumnPreset(kind, name); ok {
+		return columns.Parse(spec)
+	}
+	if spec, ok := columnPresets[kind][name]; ok {
+		return columns.Parse(spec)
+	}
+	var names []string
+	for n := range columnPresets[kind] {
+		names = append(names, n)
+	}
+	for n := range cfg.Columns[kind] {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, errors.New("no column preset " + name + " for " + kind + ", presets: " + strings.Join(names, ", "))
+}
+
+// Fields printing the usage of the current instant
+var usageFields = []string{"cpu.current", "mem.current"}
+
+// Fields printing labels of the node
+var nodeFields = []string{"node.group", "node.zone", "node.instance-type", "node.arch"}
+
+// Current cpu and memory usage of a pod or node
+type usage struct {
+	cpu, mem resource.Quantity
+}
+
+func formatCPU(q resource.Quantity) string {
+	return strconv.FormatInt(q.MilliValue(), 10) + "m"
+}
+
+func formatMem(q resource.Quantity) string {
+	return strconv.FormatInt(q.Value()/1048576, 10) + "Mi"
+}
+
+/*
+Format the usage of key, n/a when usage could not be read
+and - if metrics-server has no sample for it
+*/
+func formatUsage(all map[string]usage, key string, cpu bool) string {
+	if all == nil {
+		return metrics.NotAvailable
+	}
+	u, ok := all[key]
+	if !ok {
+		return "-"
+	}
+	if cpu {
+		return formatCPU(u.cpu)
+	}
+	return formatMem(u.mem)
+}
+
+// Check the metrics API for usage columns, with the install hint on stderr if it is not available
+func usageAvailable() (bool, error) {
+	ok, err := metrics.Available()
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		fmt.Fprint(os.Stderr, metrics.Hint+"\n\n")
+	}
+	return ok, nil
+}
+
+// Get the current usage per namespace/pod, nil without the metrics API
+func getPodUsage(namespace string) (map[string]usage, error) {
+	ok, err := usageAvailable()
+	if !ok || err != nil {
+		return nil, err
+	}
+	podMetrics, err := metrics.GetPodMetrics(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]usage, len(podMetrics.Items))
+	for _, m := range podMetrics.Items {
+		var u usage
+		for _, c := range m.Containers {
+			u.cpu.Add(c.Usage[v1.ResourceCPU])
+			u.mem.Add(c.Usage[v1.ResourceMemory])
+		}
+		all[m.Namespace+"/"+m.Name] = u
+	}
+	return all, nil
+}
+
+// Sum the usage of pods per namespace/deployment, pods are matched to deployments by their ReplicaSet
+func getDeploymentUsage(namespace string) (map[string]usage, error) {
+	podUsage, err := getPodUsage(namespace)
+	if podUsage == nil || err != nil {
+		return nil, err
+	}
+	pods, err := pod.GetPods(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := deployment.GetReplicaSets(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	deployments := deployment.GetPodDeployments(pods.Items, replicaSets.Items)
+
+	all := make(map[string]usage)
+	for key, pu := range podUsage {
+		name, ok := deployments[key]
+		if !ok {
+			continue
+		}
+		key = key[:strings.Index(key, "/")+1] + name
+		u := all[key]
+		u.cpu.Add(pu.cpu)
+		u.mem.Add(pu.mem)
+		all[key] = u
+	}
+	return all, nil
+}
+
+// Get the current usage per node, nil without the metrics API
+func getNodeUsage() (map[string]usage, error) {
+	ok, err := usageAvailable()
+	if !ok || err != nil {
+		return nil, err
+	}
+	nodeMetrics, err := metrics.GetNodeMetrics()
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]usage, len(nodeMetrics.Items))
+	for _, m := range nodeMetrics.Items {
+		all[m.Name] = usage{cpu: m.Usage[v1.ResourceCPU], mem: m.Usage[v1.ResourceMemory]}
+	}
+	return all, nil
+}
+
+// Fields derived for pods, nodes are looked up for node.* fields
+func podFields(nodes map[string]kshow.NodeSummary, podUsage map[string]usage) columns.Fields[kshow.PodRow] {
+	return columns.Fields[kshow.PodRow]{
+		"name":               func(p kshow.PodRow) string { return p.Name },
+		"namespace":          func(p kshow.PodRow) string { return p.Namespace },
+		"status":             func(p kshow.PodRow) string { return style.Status(p.Status) },
+		"ready":              func(p kshow.PodRow) string { return strconv.Itoa(p.Ready) + "/" + strconv.Itoa(p.Total) },
+		"restarts":           func(p kshow.PodRow) string { return strconv.Itoa(p.Restarts) },
+		"age":                func(p kshow.PodRow) string { return pod.FormatAge(p.Created) },
+		"node":               func(p kshow.PodRow) string { return p.Node },
+		"tenancy":            func(p kshow.PodRow) string { return p.Tenancy },
+		"node.group":         func(p kshow.PodRow) string { return nodes[p.Node].NodeGroup },
+		"node.zone":          func(p kshow.PodRow) string { return nodes[p.Node].Zone },
+		"node.instance-type": func(p kshow.PodRow) string { return nodes[p.Node].InstanceType },
+		"node.arch":          func(p kshow.PodRow) string { return nodes[p.Node].Arch },
+		"cpu.request": func(p kshow.PodRow) string {
+			return formatCPU(pod.GetPodRequests(p.Object.Spec)[v1.ResourceCPU])
+		},
+		"mem.request": func(p kshow.PodRow) string {
+			return formatMem(pod.GetPodRequests(p.Object.Spec)[v1.ResourceMemory])
+		},
+		"cpu.current": func(p kshow.PodRow) string { return formatUsage(podUsage, p.Namespace+"/"+p.Name, true) },
+		"mem.current": func(p kshow.PodRow) string { return formatUsage(podUsage, p.Namespace+"/"+p.Name, false) },
+	}
+}
+
+// Fields derived for deployments
+func deploymentFields(deploymentUsage map[string]usage) columns.Fields[kshow.DeploymentSummary] {
+	hpaField := func(f func(h *kshow.HPASummary) string) func(d kshow.DeploymentSummary) string {
+		return func(d kshow.DeploymentSummary) string {
+			if d.HPA == nil {
+				return "-"
+			}
+			return f(d.HPA)
+		}
+	}
+	return columns.Fields[kshow.DeploymentSummary]{
+		"name":      func(d kshow.DeploymentSummary) string { return d.Name },
+		"namespace": func(d kshow.DeploymentSummary) string { return d.Namespace },
+		"replicas":  func(d kshow.DeploymentSummary) string { return strconv.Itoa(int(d.Replicas)) },
+		"ready": func(d kshow.DeploymentSummary) string {
+			return strconv.Itoa(d.Running) + "/" + strconv.Itoa(int(d.Replicas))
+		},
+		"age": func(d kshow.DeploymentSummary) string { return pod.FormatAge(d.Object.CreationTimestamp.Time) },
+		"distribution": func(d kshow.DeploymentSummary) string {
+			return "OD:" + strconv.Itoa(d.OnDemand) + " SP:" + strconv.Itoa(d.Spot)
+		},
+		// PodDisruptionBudgets covering the pods, NONE is flagged
+		"pdb": func(d kshow.DeploymentSummary) string {
+			if len(d.PDBs) == 0 {
+				return "NONE"
+			}
+			return strings.Join(d.PDBs, ",")
+		},
+		"tolerations": func(d kshow.DeploymentSummary) string { return strings.Join(d.Tolerations, "::") },
+		// HorizontalPodAutoscaler scaling the deployment, its replicas override spec.replicas
+		"hpa.min":     hpaField(func(h *kshow.HPASummary) string { return strconv.Itoa(int(h.Min)) }),
+		"hpa.max":     hpaField(func(h *kshow.HPASummary) string { return strconv.Itoa(int(h.Max)) }),
+		"hpa.current": hpaField(func(h *kshow.HPASummary) string { return strconv.Itoa(int(h.Current)) }),
+		"hpa.desired": hpaField(func(h *kshow.HPASummary) string { return strconv.Itoa(int(h.Desired)) }),
+		"hpa.metrics": hpaField(func(h *kshow.HPASummary) string { return h.Metrics }),
+		"cpu.current": func(d kshow.DeploymentSummary) string {
+			return formatUsage(deploymentUsage, d.Namespace+"/"+d.Name, true)
+		},
+		"mem.current": func(d kshow.DeploymentSummary) string {
+			return formatUsage(deploymentUsage, d.Namespace+"/"+d.Name, false)
+		},
+	}
+}
+
+// Fields derived for nodes
+func nodeSummaryFields(nodeUsage map[string]usage) columns.Fields[kshow.NodeSummary] {
+	return columns.Fields[kshow.NodeSummary]{
+		"name":               func(n kshow.NodeSummary) string { return n.Name },
+		"status":             func(n kshow.NodeSummary) string { return style.Status(n.Status) },
+		"age":                func(n kshow.NodeSummary) string { return pod.FormatAge(n.Created) },
+		"version":            func(n kshow.NodeSummary) string { return n.Version },
+		"tenancy":            func(n kshow.NodeSummary) string { return n.Tenancy },
+		"node.group":         func(n kshow.NodeSummary) string { return n.NodeGroup },
+		"node.zone":          func(n kshow.NodeSummary) string { return n.Zone },
+		"node.instance-type": func(n kshow.NodeSummary) string { return n.InstanceType },
+		"node.arch":          func(n kshow.NodeSummary) string { return n.Arch },
+		"cpu.allocatable":    func(n kshow.NodeSummary) string { return formatCPU(n.AllocatableCPU) },
+		"mem.allocatable":    func(n kshow.NodeSummary) string { return formatMem(n.AllocatableMemory) },
+		"cpu.current":        func(n kshow.NodeSummary) string { return formatUsage(nodeUsage, n.Name, true) },
+		"mem.current":        func(n kshow.NodeSummary) string { return formatUsage(nodeUsage, n.Name, false) },
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"sort"
+	"text/template"
+	"time"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+	"github.com/sam0392in/kshow/internal/node"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// How long completion waits for the cluster, it suggests nothing when the cluster is slow
+const completionTimeout = 2 * time.Second
+
+// Objects of get and resource-stats, suggested by completion
+var (
+	getObjects   = []string{"deployments", "pods", "nodes", "pdbs", "images"}
+	statsObjects = []string{"pods", "deployments", "quotas", "packing"}
+)
+
+// Fish has no bash completion, it calls --completion-bash with the words typed so far
+const fishCompletionTemplate = `
+function __{{.App.Name}}_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    {{.App.Name}} --completion-bash $args
+end
+complete -c {{.App.Name}} -f -a '(__{{.App.Name}}_complete)'
+`
+
+/*
+Print the completion script of a shell, the scripts call kshow --completion-bash.
+As a kubectl plugin the script completes the kshow binary, kubectl completes plugins itself
+*/
+func printCompletion(w io.Writer, shell string) error {
+	name := app.Name
+	if pluginMode() {
+		name = "kshow"
+	}
+	templates := map[string]string{
+		"bash": kingpin.BashCompletionTemplate,
+		"zsh":  kingpin.ZshCompletionTemplate,
+		"fish": fishCompletionTemplate,
+	}
+	t, err := template.New(shell).Parse(templates[shell])
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, map[string]interface{}{"App": map[string]string{"Name": name}})
+}
+
+// Clientset the hints list from, configured from the flags of the command line being completed
+var completionClient = func() (kubernetes.Interface, error) {
+	return k8sclient.GetK8sClient()
+}
+
+/*
+Suggest names listed from the cluster within completionTimeout,
+nothing if the cluster can not be reached
+*/
+func hintFromCluster(list func(ctx context.Context, clientset kubernetes.Interface) ([]string, error)) kingpin.HintAction {
+	return func() []string {
+		clientset, err := completionClient()
+		if err != nil {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+		names, err := list(ctx, clientset)
+		if err != nil {
+			return nil
+		}
+		sort.Strings(names)
+		return names
+	}
+}
+
+var completeNamespaces = hintFromCluster(func(ctx context.Context, clientset kubernetes.Interface) ([]string, error) {
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+})
+
+var completeDeployments = hintFromCluster(func(ctx context.Context, clientset kubernetes.Interface) ([]string, error) {
+	list, err := clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, d := range list.Items {
+		if !seen[d.Name] {
+			seen[d.Name] = true
+			names = append(names, "deploy/"+d.Name)
+		}
+	}
+	return names, nil
+})
+
+// Nodes and node groups, the targets of drain-preview
+var completeNodesAndGroups = hintFromCluster(func(ctx context.Context, clientset kubernetes.Interface) ([]string, error) {
+	list, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, n := range list.Items {
+		names = append(names, n.Name)
+		if group := node.GetNodeGroup(n); group != "" && !seen[group] {
+			seen[group] = true
+			names = append(names, group)
+		}
+	}
+	return names, nil
+})
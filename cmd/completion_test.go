@@ -0,0 +1,117 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPrintCompletion(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{"--completion-bash", "complete -F _kshow_bash_autocomplete kshow"}},
+		{"zsh", []string{"#compdef kshow", "--completion-bash"}},
+		{"fish", []string{"function __kshow_complete", "kshow --completion-bash $args", "complete -c kshow"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := printCompletion(&out, tt.shell); err != nil {
+			t.Fatalf("%s: printCompletion() error = %v", tt.shell, err)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("%s: printCompletion() = %q, missing %q", tt.shell, out.String(), w)
+			}
+		}
+	}
+}
+
+// Serve the hints from a fake clientset with objects
+func fakeCompletionClient(t *testing.T, objects ...runtime.Object) {
+	client := completionClient
+	t.Cleanup(func() { completionClient = client })
+	completionClient = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(objects...), nil
+	}
+}
+
+func TestHints(t *testing.T) {
+	fakeCompletionClient(t,
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "edge"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "edge", Name: "web"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "api"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "workers"}}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"eks.amazonaws.com/nodegroup": "workers"}}},
+	)
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"namespaces", completeNamespaces(), []string{"app", "edge"}},
+		{"deployments", completeDeployments(), []string{"deploy/api", "deploy/web"}},
+		{"nodes and groups", completeNodesAndGroups(), []string{"node-a", "node-b", "workers"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: hint = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestHintUsesCommandLineContext(t *testing.T) {
+	var hintContext string
+	client := completionClient
+	t.Cleanup(func() { completionClient = client })
+	completionClient = func() (kubernetes.Interface, error) {
+		hintContext = k8sclient.CurrentContext()
+		return fake.NewSimpleClientset(), nil
+	}
+
+	stdout := os.Stdout
+	devnull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devnull.Close()
+	os.Stdout = devnull
+	app.Terminate(func(int) {})
+	_, _ = app.Parse([]string{"--completion-bash", "--context", "staging", "get", "pods", "--namespace", ""})
+	os.Stdout = stdout
+	app.Terminate(os.Exit)
+
+	if hintContext != "staging" {
+		t.Errorf("namespace hint listed from context %q, want the --context of the command line", hintContext)
+	}
+}
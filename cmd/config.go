@@ -0,0 +1,93 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+	"github.com/sam0392in/kshow/internal/config"
+	"github.com/sam0392in/kshow/internal/node"
+
+	"sigs.k8s.io/yaml"
+)
+
+// The config file, loaded before the command line is parsed
+var cfg = &config.Config{}
+
+/*
+Apply the config file to the flag defaults before parsing,
+flags given on the command line still win. The namespace depends on
+--context and is resolved after parsing
+*/
+func applyConfig() {
+	if cfg.Output != "" {
+		get.GetFlag("output").Default(cfg.Output)
+	}
+
+	labels := cfg.Labels
+	for _, l := range []struct{ from, to *string }{
+		{&labels.NodeGroup, &node.Labels.NodeGroup},
+		{&labels.CapacityType, &node.Labels.CapacityType},
+		{&labels.InstanceType, &node.Labels.InstanceType},
+		{&labels.Zone, &node.Labels.Zone},
+	} {
+		if *l.from != "" {
+			*l.to = *l.from
+		}
+	}
+	node.Labels.CapacityTypeValues = labels.CapacityTypeValues
+}
+
+// Replace an alias in the first argument by the words of its command, kshow commands can not be shadowed
+func expandAlias(args []string) []string {
+	if len(args) == 0 || app.GetCommand(args[0]) != nil {
+		return args
+	}
+	alias, ok := cfg.Aliases[args[0]]
+	if !ok {
+		return args
+	}
+	return append(strings.Fields(alias), args[1:]...)
+}
+
+// Print the config file
+func viewConfig() error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Println("# " + config.Path())
+	fmt.Print(string(data))
+	return nil
+}
+
+// Set a key of the config file, namespace is the namespace of the current context
+func setConfig(key, value string) error {
+	if key == "namespace" {
+		context := k8sclient.CurrentContext()
+		if context == "" {
+			return fmt.Errorf("no current context in the kubeconfig, set namespaces.<context> instead")
+		}
+		key = "namespaces." + context
+	}
+	if err := cfg.Set(key, value); err != nil {
+		return err
+	}
+	return cfg.Save()
+}
@@ -17,103 +17,306 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
 
+	"github.com/sam0392in/kshow/internal/audit"
+	"github.com/sam0392in/kshow/internal/cache"
+	"github.com/sam0392in/kshow/internal/config"
+	"github.com/sam0392in/kshow/internal/cost"
 	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/history"
+	"github.com/sam0392in/kshow/internal/kerrors"
 	"github.com/sam0392in/kshow/internal/metrics"
-	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/packing"
 	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/prometheus"
+	"github.com/sam0392in/kshow/internal/simulate"
+	"github.com/sam0392in/kshow/internal/style"
 
-	"go.uber.org/zap"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
-var (
-	logger *zap.Logger
+// How long --informers waits for the caches to fill
+const informerSyncTimeout = 2 * time.Minute
+
+const namespaceHelp = "Specify namespace. default is the namespace of the config file, or the namespace of the kubeconfig context. -A for all namespaces"
 
+var (
 	app = kingpin.New("kshow", "A command-line tool for kubernetes.")
 
-	get       = app.Command("get", "get details of kubernetes objects")
-	k8sObject = get.Arg("k8s object", "allowed objects: deployment, pods").Required().String()
-	namespace = get.Flag("namespace", "Specify namespace. default is all namespace").Short('n').Default("").String()
-	detailed  = get.Flag("detailed", "Show extra details").Bool()
+	informers = app.Flag("informers", "Read objects from informer caches shared by the whole command instead of listing them per lookup").Bool()
 
-	resourceStats  = app.Command("resource-stats", "Show current resource statistics")
-	statsk8sObject = resourceStats.Arg("k8s object", "allowed objects: deployment, pods").String()
-	statsNamespace = resourceStats.Flag("namespace", "Specify namespace. default is all namespace").Short('n').Default("").String()
-	statsDetailed  = resourceStats.Flag("detailed", "show detailed resource statistics").Bool()
-)
+	// kubectl flags, with the same semantics as kubectl
+	kubeconfig     = app.Flag("kubeconfig", "Path to the kubeconfig file to use for CLI requests").String()
+	kubeContext    = app.Flag("context", "The name of the kubeconfig context to use").String()
+	as             = app.Flag("as", "Username to impersonate for the operation").String()
+	requestTimeout = app.Flag("request-timeout", "The length of time to wait before giving up on a single server request, e.g. 1s, 2m. 0 waits forever").Default("0").String()
+	allNamespaces  = app.Flag("all-namespaces", "List the requested objects across all namespaces, overrides --namespace").Short('A').Bool()
+	prometheusURL  = app.Flag("prometheus-url", "Read usage from the cAdvisor series of this Prometheus instead of metrics-server, e.g. http://prometheus.monitoring:9090").String()
+	color          = app.Flag("color", "Color statuses and usage: always, never or auto, auto colors a terminal unless NO_COLOR is set").Default(style.Auto).Enum(style.Always, style.Never, style.Auto)
+
+	get           = app.Command("get", "get details of kubernetes objects")
+	k8sObject     = get.Arg("k8s object", "allowed objects: deployment, pods, nodes, pdbs, images").Required().HintOptions(getObjects...).String()
+	namespace     = get.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+	detailed      = get.Flag("detailed", "Show extra details").Bool()
+	containers    = get.Flag("containers", "List every container of the pods with its type").Bool()
+	resources     = get.Flag("resources", "Show QoS class and requests and limits completeness").Bool()
+	registry      = get.Flag("registry", "Only images pulled from this registry, e.g. docker.io").String()
+	outdated      = get.Flag("outdated-than", "Only images of pods older than this, e.g. 720h").Duration()
+	output        = get.Flag("output", "Output format of pods, nodes and deployments: custom-columns=HEADER:field,... where field is a .jsonpath or a kshow field, e.g. NAME:.metadata.name,ZONE:node.zone").Short('o').String()
+	columnsPreset = get.Flag("columns", "Column preset of pods, nodes and deployments: default, detailed, usage or one from the config file").String()
+
+	resourceStats    = app.Command("resource-stats", "Show current resource statistics")
+	statsk8sObject   = resourceStats.Arg("k8s object", "allowed objects: deployment, pods, quotas, packing, and nodes with --from").HintOptions(statsObjects...).String()
+	statsNamespace   = resourceStats.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+	statsDetailed    = resourceStats.Flag("detailed", "show detailed resource statistics").Bool()
+	requireMetrics   = resourceStats.Flag("require-metrics", "Exit with code 5 instead of showing usage as n/a when the metrics API is not available").Bool()
+	highlightRequest = resourceStats.Flag("highlight-request", "Color container usage over this percent of its request yellow").Default("100").Int()
+	highlightLimit   = resourceStats.Flag("highlight-limit", "Color container usage over this percent of its limit red").Default("90").Int()
+	statsFrom        = resourceStats.Flag("from", "Show min, avg, p95 and max of pods, deployments or nodes from a file written by kshow record").ExistingFile()
+	statsWindow      = resourceStats.Flag("window", "With --from, only the samples of this long before the last one, e.g. 6h. With --prometheus-url, average and peak usage and cpu throttling over this window").Duration()
+
+	recordCmd       = app.Command("record", "Sample pod and node usage from metrics-server or --prometheus-url into a file for resource-stats --from")
+	recordInterval  = recordCmd.Flag("interval", "Time between samples").Default("30s").Duration()
+	recordDuration  = recordCmd.Flag("duration", "How long to record").Default("24h").Duration()
+	recordOut       = recordCmd.Flag("out", "File the samples are appended to").Required().String()
+	recordNamespace = recordCmd.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+
+	auditCmd       = app.Command("audit", "Audit deployments for common best-practice issues")
+	auditNamespace = auditCmd.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+	auditOutput    = auditCmd.Flag("output", "Output format: table, json").Short('o').Default("table").Enum("table", "json")
+	auditFailOn    = auditCmd.Flag("fail-on", "Exit with code 7 if any finding is at or above this severity: high, medium, low, none. Other errors exit with their own codes").Default("high").Enum("high", "medium", "low", "none")
+
+	costCmd       = app.Command("cost", "Estimate hourly and monthly cost per namespace, deployment or team")
+	costNamespace = costCmd.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+	costPrices    = costCmd.Flag("prices", "Price table file: instance type and ON_DEMAND/SPOT to hourly price").Required().ExistingFile()
+	costBy        = costCmd.Flag("by", "Apportion node cost by pod requests or current usage").Default("requests").Enum("requests", "usage")
+	costGroupBy   = costCmd.Flag("group-by", "Roll up cost per namespace, deployment or team").Default("namespace").Enum("namespace", "deployment", "team")
+	costTeamLabel = costCmd.Flag("team-label", "Pod label holding the team name").Default("team").String()
+
+	simulateCmd       = app.Command("simulate", "Simulate changes against current cluster capacity")
+	simulateScale     = simulateCmd.Command("scale", "Simulate scaling a deployment")
+	simulateTarget    = simulateScale.Arg("deployment", "deployment to scale, e.g. deploy/foo").Required().HintAction(completeDeployments).String()
+	simulateReplicas  = simulateScale.Flag("replicas", "Target number of replicas").Required().Int()
+	simulateNamespace = simulateScale.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+
+	drainPreview       = app.Command("drain-preview", "Preview the impact of draining a node or a node group")
+	drainPreviewTarget = drainPreview.Arg("node|nodegroup", "node name or node group to drain").Required().HintAction(completeNodesAndGroups).String()
+
+	rolloutCmd       = app.Command("rollout", "Show rollout progress and revision history of a deployment")
+	rolloutTarget    = rolloutCmd.Arg("deployment", "deployment name, e.g. deploy/foo").Required().HintAction(completeDeployments).String()
+	rolloutNamespace = rolloutCmd.Flag("namespace", namespaceHelp).Short('n').Action(namespaceGiven).HintAction(completeNamespaces).String()
+
+	configCmd   = app.Command("config", "View or change the config file ~/.config/kshow/config.yaml")
+	configView  = configCmd.Command("view", "Print the config file")
+	configSet   = configCmd.Command("set", "Set a key of the config file, an empty value unsets it")
+	configKey   = configSet.Arg("key", "namespace, namespaces.<context>, output, labels.<name>, labels.capacityTypeValues.<value>, columns.<kind>.<preset> or aliases.<name>").Required().String()
+	configValue = configSet.Arg("value", "value of the key").String()
 
-func init() {
-	logger, _ = zap.NewProduction()
+	completionCmd   = app.Command("completion", "Print the shell completion script, e.g. source <(kshow completion bash)")
+	completionShell = completionCmd.Arg("shell", "bash, zsh or fish").Required().Enum("bash", "zsh", "fish")
+)
 
+/*
+Print one line for an error and exit with its code,
+see kerrors for the codes of auth, connection, metrics and not found errors
+*/
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "kshow: "+kerrors.Describe(err))
+	os.Exit(kerrors.ExitCode(err))
 }
 
-func getDeployments() {
-	if *detailed {
-		deployment.ListDeploymentDetailed(*namespace)
-	} else {
-		deployment.ListDeployments(*namespace)
+func getDeployments() error {
+	if *resources {
+		return deployment.ListDeploymentResources(*namespace)
 	}
+	cols, err := getColumns("deployments")
+	if err != nil {
+		return err
+	}
+	return printDeployments(*namespace, cols)
 }
 
-func getPods() {
-	if *detailed {
-		pod.ListPodswithNodeTenency(*namespace)
-	} else {
-		pod.ListPods(*namespace)
+func getPods() error {
+	if *containers {
+		return pod.ListPodContainers(*namespace)
+	} else if *resources {
+		return pod.ListPodResources(*namespace)
+	}
+	cols, err := getColumns("pods")
+	if err != nil {
+		return err
 	}
+	return printPods(*namespace, cols)
 }
 
-func getNodes() {
-	if *detailed {
-		node.DetailedNodeInfo()
-	} else {
-		node.GetNodeDetails()
+func getNodes() error {
+	cols, err := getColumns("nodes")
+	if err != nil {
+		return err
 	}
+	return printNodes(cols)
 }
 
-func getMetrics() {
+/*
+Print resource statistics, usage columns are n/a with a hint on stderr
+when the metrics API is not available, unless --require-metrics is set.
+With --from the stats come from a recording instead of the cluster
+*/
+func getMetrics() error {
+	if *statsFrom != "" {
+		return history.PrintStats(*statsFrom, *statsWindow, *statsk8sObject, *statsNamespace)
+	}
+	if *statsWindow != 0 {
+		return metrics.PrintWindowUsage(*statsNamespace, *statsWindow)
+	}
+	if *statsk8sObject == "packing" {
+		return packing.PrintPacking()
+	}
+	withUsage, err := metrics.Available()
+	if err != nil {
+		return err
+	}
+	if !withUsage {
+		if *requireMetrics {
+			return kerrors.ErrMetricsUnavailable
+		}
+		defer fmt.Fprintln(os.Stderr, "\n"+metrics.Hint)
+	}
 	switch *statsk8sObject {
 	case "deployment", "deployments", "deploy":
-		metrics.GetDeploymentsMetrics(*statsNamespace)
-	case "pods", "pod", "po":
-		if *statsDetailed {
-			metrics.PrintContainerMetrics(*statsNamespace)
-		} else {
-			metrics.PrintPodMetrics(*statsNamespace)
-		}
-	default:
-		if *statsDetailed {
-			metrics.PrintContainerMetrics(*statsNamespace)
-		} else {
-			metrics.PrintPodMetrics(*statsNamespace)
-		}
+		return metrics.GetDeploymentsMetrics(*statsNamespace, withUsage)
+	case "quotas", "quota", "resourcequotas", "resourcequota":
+		return metrics.PrintQuotaUsage(*statsNamespace, withUsage)
 	}
+	if *statsDetailed {
+		return printContainerMetrics(*statsNamespace, withUsage)
+	}
+	return metrics.PrintPodMetrics(*statsNamespace, withUsage)
+}
+
+func runAudit() error {
+	findings, err := audit.GetFindings(*auditNamespace)
+	if err != nil {
+		return err
+	}
+	if err := audit.PrintFindings(findings, *auditOutput); err != nil {
+		return err
+	}
+	if audit.Failed(findings, *auditFailOn) {
+		return kerrors.ErrAuditFailed
+	}
+	return nil
 }
 
 func getTest() {
 	// node.GetNodeCountPerNG()
 }
 
-func getObject() {
+func getObject() error {
 	switch *k8sObject {
 	case "deployment", "deployments", "deploy":
-		getDeployments()
+		return getDeployments()
 	case "pods", "pod", "po":
-		getPods()
+		return getPods()
 	case "node", "nodes", "no":
-		getNodes()
+		return getNodes()
+	case "pdb", "pdbs", "poddisruptionbudget", "poddisruptionbudgets":
+		return deployment.ListPodDisruptionBudgets(*namespace)
+	case "image", "images":
+		return pod.ListImages(*namespace, *registry, *outdated)
 	case "test":
 		getTest()
 	}
+	return nil
+}
+
+/*
+Namespace the informers of --informers are scoped to, the resolved namespace of
+the command. Commands reading pods of every namespace get all of them, nodes are
+always cached cluster wide
+*/
+func informerNamespace(command string) string {
+	switch command {
+	case get.FullCommand():
+		switch *k8sObject {
+		case "node", "nodes", "no":
+			return ""
+		}
+		return *namespace
+	case resourceStats.FullCommand():
+		if *statsk8sObject == "packing" {
+			return ""
+		}
+		return *statsNamespace
+	case auditCmd.FullCommand():
+		return *auditNamespace
+	case costCmd.FullCommand():
+		return *costNamespace
+	case rolloutCmd.FullCommand():
+		return *rolloutNamespace
+	case recordCmd.FullCommand():
+		return *recordNamespace
+	}
+	return ""
 }
 
 func main() {
-	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	if pluginMode() {
+		app.Name = "kubectl kshow"
+	}
+	// a config file with a bad key fails every command but config set, which fixes it
+	loaded, loadErr := config.Load()
+	if loadErr == nil {
+		cfg = loaded
+		applyConfig()
+	}
+	command, parseErr := app.Parse(expandAlias(os.Args[1:]))
+	if loadErr != nil && command != configSet.FullCommand() {
+		exitOnError(loadErr)
+	}
+	kingpin.MustParse(command, parseErr)
+	var err error
+	if loadErr != nil {
+		cfg, err = config.LoadLenient()
+		exitOnError(err)
+	}
+	exitOnError(resolveNamespace(command))
+	exitOnError(style.Configure(*color))
+	if *prometheusURL != "" {
+		metrics.SetSource(prometheus.New(*prometheusURL))
+	}
+	if *informers {
+		exitOnError(cache.UseInformers(informerNamespace(command), informerSyncTimeout))
+	}
+	switch command {
 	case get.FullCommand():
-		getObject()
+		err = getObject()
 	case resourceStats.FullCommand():
-		getMetrics()
+		err = getMetrics()
+	case auditCmd.FullCommand():
+		err = runAudit()
+	case costCmd.FullCommand():
+		err = cost.PrintCost(*costNamespace, *costPrices, *costBy, *costGroupBy, *costTeamLabel)
+	case simulateScale.FullCommand():
+		err = simulate.PrintScale(*simulateNamespace, *simulateTarget, *simulateReplicas)
+	case drainPreview.FullCommand():
+		err = pod.PrintDrainPreview(*drainPreviewTarget)
+	case rolloutCmd.FullCommand():
+		err = deployment.PrintRollout(*rolloutNamespace, *rolloutTarget)
+	case configView.FullCommand():
+		err = viewConfig()
+	case configSet.FullCommand():
+		err = setConfig(*configKey, *configValue)
+	case recordCmd.FullCommand():
+		err = record()
+	case completionCmd.FullCommand():
+		err = printCompletion(os.Stdout, *completionShell)
 	}
+	exitOnError(err)
 }
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs main with the arguments of KSHOW_TEST_ARGS when the test binary is started by runKshow
+func TestKshowMain(t *testing.T) {
+	if os.Getenv("KSHOW_TEST_MAIN") != "1" {
+		t.Skip("run by runKshow")
+	}
+	os.Args = append([]string{"kshow"}, strings.Split(os.Getenv("KSHOW_TEST_ARGS"), "\n")...)
+	main()
+	os.Exit(0)
+}
+
+// Run kshow in a subprocess with HOME set to home and no kubeconfig, returns its output and exit code
+func runKshow(t *testing.T, home string, args ...string) (string, int) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestKshowMain$")
+	cmd.Env = append(os.Environ(),
+		"KSHOW_TEST_MAIN=1",
+		"KSHOW_TEST_ARGS="+strings.Join(args, "\n"),
+		"HOME="+home,
+		"XDG_CONFIG_HOME=",
+		"KUBECONFIG="+filepath.Join(home, "missing-kubeconfig"),
+	)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+func TestMainWithoutKubeconfig(t *testing.T) {
+	from := filepath.Join(t.TempDir(), "rec.jsonl")
+	sample := `{"time":"2024-01-01T00:00:00Z","containers":[` +
+		`{"namespace":"team-a","pod":"web-1","container":"web","cpu":100,"mem":1024},` +
+		`{"namespace":"team-b","pod":"api-1","container":"api","cpu":200,"mem":2048}]}` + "\n"
+	if err := os.WriteFile(from, []byte(sample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"config", "view"}, nil},
+		{[]string{"config", "set", "output", "wide"}, nil},
+		// a recording is not filtered by a namespace of the current context
+		{[]string{"resource-stats", "pods", "--from", from}, []string{"web-1", "api-1"}},
+	}
+	for _, tt := range tests {
+		out, code := runKshow(t, t.TempDir(), tt.args...)
+		if code != 0 {
+			t.Errorf("kshow %s exited with %d: %s", strings.Join(tt.args, " "), code, out)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("kshow %s output is missing %q:\n%s", strings.Join(tt.args, " "), want, out)
+			}
+		}
+	}
+}
+
+// Cluster hints are best effort, completion works without a cluster config
+func TestCompletionWithoutKubeconfig(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"completion", "bash"}, "complete -F _kshow_bash_autocomplete kshow"},
+		{[]string{"completion", "zsh"}, "compdef"},
+		{[]string{"completion", "fish"}, "complete -c kshow"},
+		{[]string{"--completion-bash", "get"}, "deployment"},
+		{[]string{"--completion-bash", "get", "pods", "-n"}, ""},
+	}
+	for _, tt := range tests {
+		out, code := runKshow(t, t.TempDir(), tt.args...)
+		if code != 0 {
+			t.Errorf("kshow %s exited with %d: %s", strings.Join(tt.args, " "), code, out)
+		} else if !strings.Contains(out, tt.want) {
+			t.Errorf("kshow %s output is missing %q:\n%s", strings.Join(tt.args, " "), tt.want, out)
+		}
+	}
+}
+
+// A config file with a bad key fails every command but config set, which fixes it
+func TestConfigSetFixesBadKey(t *testing.T) {
+	home := t.TempDir()
+	path := filepath.Join(home, ".config", "kshow", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("colums: {}\nnamespaces:\n  dev: team-a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runKshow(t, home, "config", "view"); code == 0 || !strings.Contains(out, "colums") {
+		t.Errorf("config view of a bad key exited with %d: %s", code, out)
+	}
+	if out, code := runKshow(t, home, "config", "set", "output", "wide"); code != 0 {
+		t.Fatalf("config set exited with %d: %s", code, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "colums") || !strings.Contains(string(data), "team-a") || !strings.Contains(string(data), "wide") {
+		t.Errorf("config set wrote:\n%s", data)
+	}
+	if out, code := runKshow(t, home, "config", "view"); code != 0 {
+		t.Errorf("config view exited with %d after config set: %s", code, out)
+	}
+}
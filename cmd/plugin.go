@@ -0,0 +1,106 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Set when --namespace is given on the command line
+var namespaceSet bool
+
+func namespaceGiven(*kingpin.ParseContext) error {
+	namespaceSet = true
+	return nil
+}
+
+/*
+Apply the kubectl flags to the client, as a pre-action so it also runs
+before the completion hints list objects from the cluster
+*/
+func configureClient(*kingpin.ParseContext) error {
+	k8sclient.Configure(k8sclient.Options{Kubeconfig: *kubeconfig, Context: *kubeContext, As: *as, RequestTimeout: *requestTimeout})
+	return nil
+}
+
+func init() {
+	app.PreAction(configureClient)
+}
+
+// Running as a kubectl plugin, the binary is named kubectl-kshow
+func pluginMode() bool {
+	return strings.HasPrefix(filepath.Base(os.Args[0]), "kubectl-")
+}
+
+/*
+Get the namespace flag of a command reading namespaced objects from the cluster,
+nil for commands that do not. resource-stats --from reads a recording,
+whose cluster may not be the current context
+*/
+func namespaceFlag(command string) *string {
+	switch command {
+	case get.FullCommand():
+		return namespace
+	case resourceStats.FullCommand():
+		if *statsFrom != "" {
+			return nil
+		}
+		return statsNamespace
+	case auditCmd.FullCommand():
+		return auditNamespace
+	case costCmd.FullCommand():
+		return costNamespace
+	case simulateScale.FullCommand():
+		return simulateNamespace
+	case rolloutCmd.FullCommand():
+		return rolloutNamespace
+	case recordCmd.FullCommand():
+		return recordNamespace
+	}
+	return nil
+}
+
+/*
+Resolve the namespace of the command like kubectl: -A for all namespaces, else
+--namespace, else the namespace of the context in the config file, else the
+namespace of the kubeconfig context. Commands without a cluster namespace skip
+this, they run without a kubeconfig
+*/
+func resolveNamespace(command string) error {
+	ns := namespaceFlag(command)
+	switch {
+	case ns == nil:
+	case *allNamespaces:
+		*ns = ""
+	case namespaceSet:
+	case cfg.Namespaces[k8sclient.CurrentContext()] != "":
+		*ns = cfg.Namespaces[k8sclient.CurrentContext()]
+	default:
+		contextNamespace, err := k8sclient.ContextNamespace()
+		if err != nil {
+			return err
+		}
+		*ns = contextNamespace
+	}
+	return nil
+}
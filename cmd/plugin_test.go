@@ -0,0 +1,93 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+	"github.com/sam0392in/kshow/internal/config"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+- name: dev
+  context: {cluster: dev, user: dev, namespace: team-a}
+- name: prod
+  context: {cluster: dev, user: dev}
+clusters:
+- name: dev
+  cluster: {server: "https://127.0.0.1:6443"}
+users:
+- name: dev
+  user: {}
+`
+
+func TestResolveNamespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		k8sclient.Configure(k8sclient.Options{})
+		cfg = &config.Config{}
+		*allNamespaces, namespaceSet, *namespace, *statsNamespace, *statsFrom = false, false, "", "", ""
+	})
+
+	tests := []struct {
+		name       string
+		command    string
+		from       string
+		kubeconfig string
+		context    string
+		all, given bool
+		configured map[string]string
+		flag       *string
+		want       string
+	}{
+		{"context namespace", "get", "", path, "", false, false, nil, namespace, "team-a"},
+		{"context without namespace", "get", "", path, "prod", false, false, nil, namespace, "default"},
+		{"config file", "get", "", path, "", false, false, map[string]string{"dev": "team-b"}, namespace, "team-b"},
+		{"all namespaces", "get", "", path, "", true, true, map[string]string{"dev": "team-b"}, namespace, ""},
+		{"flag", "get", "", path, "", false, true, nil, namespace, "from-flag"},
+		{"stats", "resource-stats", "", path, "", false, false, nil, statsNamespace, "team-a"},
+		// a recording is not filtered by the current context
+		{"stats from a recording", "resource-stats", "rec.jsonl", path, "", false, false, nil, statsNamespace, ""},
+		// commands without a cluster namespace run without a kubeconfig
+		{"completion", "completion", "", filepath.Join(t.TempDir(), "missing"), "", false, false, nil, namespace, ""},
+		{"config", "config set", "", filepath.Join(t.TempDir(), "missing"), "", false, false, nil, namespace, ""},
+	}
+	for _, tt := range tests {
+		k8sclient.Configure(k8sclient.Options{Kubeconfig: tt.kubeconfig, Context: tt.context})
+		cfg = &config.Config{Namespaces: tt.configured}
+		*allNamespaces, namespaceSet, *statsFrom = tt.all, tt.given, tt.from
+		*namespace, *statsNamespace = "", ""
+		if tt.given {
+			*tt.flag = "from-flag"
+		}
+		if err := resolveNamespace(tt.command); err != nil {
+			t.Fatalf("%s: resolveNamespace() error = %v", tt.name, err)
+		}
+		if *tt.flag != tt.want {
+			t.Errorf("%s: namespace = %q, want %q", tt.name, *tt.flag, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+	"github.com/sam0392in/kshow/internal/columns"
+	"github.com/sam0392in/kshow/internal/metrics"
+	"github.com/sam0392in/kshow/internal/style"
+	"github.com/sam0392in/kshow/pkg/kshow"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+const lineBreaker = "--------------------------------------------------------------------------------------------------------------------------------------"
+
+func clientset() (kubernetes.Interface, error) {
+	return k8sclient.GetK8sClient()
+}
+
+// Print pods in columns, --detailed prints only scheduled pods
+func printPods(namespace string, cols []columns.Column) error {
+	cs, err := clientset()
+	if err != nil {
+		return err
+	}
+	rows, err := kshow.ListPods(cs, kshow.Options{Namespace: namespace})
+	if err != nil {
+		return err
+	}
+	if *detailed {
+		scheduled := rows[:0]
+		for _, p := range rows {
+			if p.Node != "" {
+				scheduled = append(scheduled, p)
+			}
+		}
+		rows = scheduled
+	}
+
+	var nodes map[string]kshow.NodeSummary
+	if columns.Uses(cols, nodeFields...) {
+		summaries, err := kshow.ListNodes(cs)
+		if err != nil {
+			return err
+		}
+		nodes = make(map[string]kshow.NodeSummary, len(summaries))
+		for _, n := range summaries {
+			nodes[n.Name] = n
+		}
+	}
+	var podUsage map[string]usage
+	if columns.Uses(cols, usageFields...) {
+		if podUsage, err = getPodUsage(namespace); err != nil {
+			return err
+		}
+	}
+	return columns.Print(style.Stdout, cols, rows, podFields(nodes, podUsage), func(p kshow.PodRow) runtime.Object { return p.Object })
+}
+
+// Print deployments in columns
+func printDeployments(namespace string, cols []columns.Column) error {
+	cs, err := clientset()
+	if err != nil {
+		return err
+	}
+	deployments, err := kshow.ListDeployments(cs, kshow.Options{Namespace: namespace})
+	if err != nil {
+		return err
+	}
+	var deploymentUsage map[string]usage
+	if columns.Uses(cols, usageFields...) {
+		if deploymentUsage, err = getDeploymentUsage(namespace); err != nil {
+			return err
+		}
+	}
+	return columns.Print(style.Stdout, cols, deployments, deploymentFields(deploymentUsage), func(d kshow.DeploymentSummary) runtime.Object { return d.Object })
+}
+
+// Print kubelet versions and node count per node group
+func printNodeHeader(nodes []kshow.NodeSummary) {
+	var versions, nodeGroups []string
+	seenVersion := make(map[string]bool)
+	count := make(map[string]int)
+	for _, n := range nodes {
+		if !seenVersion[n.Version] {
+			seenVersion[n.Version] = true
+			versions = append(versions, n.Version)
+		}
+		if _, ok := count[n.NodeGroup]; !ok {
+			nodeGroups = append(nodeGroups, n.NodeGroup)
+		}
+		count[n.NodeGroup]++
+	}
+
+	fmt.Println(lineBreaker)
+	fmt.Println("K8S-VERSION\t\t\tNODE-GROUP: NODECOUNT")
+	for i, ng := range nodeGroups {
+		if i < len(versions) {
+			fmt.Println(versions[i] + "\t\t" + ng + ":  " + strconv.Itoa(count[ng]))
+		} else {
+			fmt.Println("\t\t\t\t" + ng + ":  " + strconv.Itoa(count[ng]))
+		}
+	}
+	fmt.Println(lineBreaker)
+}
+
+// Print nodes in columns, --detailed prints kubelet versions and node groups above
+func printNodes(cols []columns.Column) error {
+	cs, err := clientset()
+	if err != nil {
+		return err
+	}
+	nodes, err := kshow.ListNodes(cs)
+	if err != nil {
+		return err
+	}
+	var nodeUsage map[string]usage
+	if columns.Uses(cols, usageFields...) {
+		if nodeUsage, err = getNodeUsage(); err != nil {
+			return err
+		}
+	}
+	if *detailed {
+		printNodeHeader(nodes)
+		fmt.Println()
+	}
+	return columns.Print(style.Stdout, cols, nodes, nodeSummaryFields(nodeUsage), func(n kshow.NodeSummary) runtime.Object { return n.Object })
+}
+
+// Color a usage over --highlight-request percent of its request or --highlight-limit percent of its limit
+func highlightUsage(usage, request, limit int64) style.Color {
+	return style.UsageColor(usage, request, limit, *highlightRequest, *highlightLimit)
+}
+
+// Print container usage against requests and limits, usage is n/a without the metrics API
+func printContainerMetrics(namespace string, withUsage bool) error {
+	var source kshow.PodMetricsLister
+	if withUsage {
+		source = metrics.GetSource()
+	}
+	cs, err := clientset()
+	if err != nil {
+		return err
+	}
+	usage, err := kshow.ListContainerUsageFrom(cs, source, kshow.Options{Namespace: namespace})
+	if err != nil {
+		return err
+	}
+
+	if err := metrics.PrintResourceHeader(namespace, withUsage); err != nil {
+		return err
+	}
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\t\tPOD\t\tCONTAINER\t\tTYPE\t\tCURRENT-CPU\t\tREQ-CPU\t\tLIMIT-CPU\t\tCURRENT-MEM\t\tREQ-MEM\t\tLIMIT-MEM")
+	for _, u := range usage {
+		cpu, mem := metrics.NotAvailable, metrics.NotAvailable
+		if u.HasUsage {
+			cpu = style.Paint(highlightUsage(u.CPU.MilliValue(), u.RequestCPU.MilliValue(), u.LimitCPU.MilliValue()), strconv.Itoa(int(u.CPU.MilliValue()))+"m")
+			mem = style.Paint(highlightUsage(u.Memory.Value(), u.RequestMem.Value(), u.LimitMem.Value()), strconv.Itoa(int(u.Memory.Value()/1048859))+"Mi")
+		}
+		data := u.Namespace + "\t\t" + u.Pod + "\t\t" + u.Container + "\t\t" + u.Type + "\t\t" + cpu + "\t\t" + u.RequestCPU.String() + "\t\t" + u.LimitCPU.String() + "\t\t" + mem + "\t\t" + u.RequestMem.String() + "\t\t" + u.LimitMem.String()
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/history"
+	"github.com/sam0392in/kshow/internal/metrics"
+	"github.com/sam0392in/kshow/internal/pod"
+)
+
+// Record pod and node usage until --duration has passed or kshow is interrupted
+func record() error {
+	if err := metrics.Require(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Recording every %s for %s to %s, stop with Ctrl-C\n", *recordInterval, *recordDuration, *recordOut)
+	return history.Record(ctx, *recordOut, *recordInterval, *recordDuration, sampleUsage)
+}
+
+// Sample pod and node usage, a sample in flight is cancelled with ctx
+func sampleUsage(ctx context.Context) (history.Sample, error) {
+	source := metrics.GetSource()
+	pods, err := source.PodMetrics(ctx, *recordNamespace)
+	if err != nil {
+		return history.Sample{}, err
+	}
+	nodes, err := source.NodeMetrics(ctx)
+	if err != nil {
+		return history.Sample{}, err
+	}
+	podList, err := pod.GetPods(recordNamespace)
+	if err != nil {
+		return history.Sample{}, err
+	}
+	rsList, err := deployment.GetReplicaSets(recordNamespace)
+	if err != nil {
+		return history.Sample{}, err
+	}
+	return history.NewSample(time.Now(), pods, nodes, deployment.GetPodDeployments(podList.Items, rsList.Items)), nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sam0392in/kshow/internal/metrics"
+
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// ctxSource fails like a request cancelled with its context
+type ctxSource struct{}
+
+func (ctxSource) Available() (bool, error) { return true, nil }
+
+func (ctxSource) PodMetrics(ctx context.Context, namespace string) (*v1beta1.PodMetricsList, error) {
+	return &v1beta1.PodMetricsList{}, ctx.Err()
+}
+
+func (ctxSource) NodeMetrics(ctx context.Context) (*v1beta1.NodeMetricsList, error) {
+	return &v1beta1.NodeMetricsList{}, ctx.Err()
+}
+
+func TestSampleUsageCancelled(t *testing.T) {
+	defer metrics.SetSource(metrics.GetSource())
+	metrics.SetSource(ctxSource{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sampleUsage(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("sampleUsage() of a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
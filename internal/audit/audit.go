@@ -0,0 +1,277 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pdb"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// Severities of findings, highest first
+const (
+	SeverityHigh   = "HIGH"
+	SeverityMedium = "MEDIUM"
+	SeverityLow    = "LOW"
+)
+
+var severityRank = map[string]int{
+	SeverityHigh:   3,
+	SeverityMedium: 2,
+	SeverityLow:    1,
+}
+
+// Finding is a single issue found on a workload
+type Finding struct {
+	Severity   string `json:"severity"`
+	Check      string `json:"check"`
+	Namespace  string `json:"namespace"`
+	Deployment string `json:"deployment"`
+	Container  string `json:"container,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Split an image reference into its tag and whether it is pinned to a digest
+func parseImage(image string) (tag string, pinned bool) {
+	if strings.Contains(image, "@") {
+		pinned = true
+		image = image[:strings.Index(image, "@")]
+	}
+	// a colon before the last slash is a registry port, not a tag
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		tag = name[i+1:]
+	}
+	return tag, pinned
+}
+
+func checkResources(c v1.Container) []Finding {
+	var findings []Finding
+	r := pod.GetContainerResources(c.Resources)
+	if r.RequestCPU.IsZero() || r.RequestMem.IsZero() {
+		findings = append(findings, Finding{Severity: SeverityHigh, Check: "missing-requests", Container: c.Name, Message: "cpu or memory request is not set"})
+	}
+	if r.LimitCPU.IsZero() || r.LimitMem.IsZero() {
+		findings = append(findings, Finding{Severity: SeverityMedium, Check: "missing-limits", Container: c.Name, Message: "cpu or memory limit is not set"})
+	}
+	return findings
+}
+
+func checkImage(c v1.Container) []Finding {
+	var findings []Finding
+	tag, pinned := parseImage(c.Image)
+	switch {
+	case pinned:
+		if c.ImagePullPolicy == v1.PullAlways {
+			findings = append(findings, Finding{Severity: SeverityLow, Check: "pull-always-digest", Container: c.Name, Message: "imagePullPolicy Always on an image pinned to a digest"})
+		}
+	case tag == "":
+		findings = append(findings, Finding{Severity: SeverityHigh, Check: "image-tag", Container: c.Name, Message: "image " + c.Image + " is untagged"})
+	case tag == "latest":
+		findings = append(findings, Finding{Severity: SeverityHigh, Check: "image-tag", Container: c.Name, Message: "image " + c.Image + " uses the latest tag"})
+	}
+	return findings
+}
+
+func checkProbes(c v1.Container) []Finding {
+	var findings []Finding
+	if c.ReadinessProbe == nil {
+		findings = append(findings, Finding{Severity: SeverityMedium, Check: "readiness-probe", Container: c.Name, Message: "no readiness probe"})
+	}
+	if c.LivenessProbe == nil {
+		findings = append(findings, Finding{Severity: SeverityLow, Check: "liveness-probe", Container: c.Name, Message: "no liveness probe"})
+	}
+	return findings
+}
+
+// Container settings override the pod security context
+func checkRunAsRoot(spec v1.PodSpec, c v1.Container) []Finding {
+	var (
+		runAsUser    *int64
+		runAsNonRoot *bool
+	)
+	if spec.SecurityContext != nil {
+		runAsUser = spec.SecurityContext.RunAsUser
+		runAsNonRoot = spec.SecurityContext.RunAsNonRoot
+	}
+	if c.SecurityContext != nil {
+		if c.SecurityContext.RunAsUser != nil {
+			runAsUser = c.SecurityContext.RunAsUser
+		}
+		if c.SecurityContext.RunAsNonRoot != nil {
+			runAsNonRoot = c.SecurityContext.RunAsNonRoot
+		}
+	}
+	switch {
+	case runAsUser != nil && *runAsUser == 0:
+		return []Finding{{Severity: SeverityHigh, Check: "run-as-root", Container: c.Name, Message: "runs as root (runAsUser 0)"}}
+	case runAsUser == nil && (runAsNonRoot == nil || !*runAsNonRoot):
+		return []Finding{{Severity: SeverityMedium, Check: "run-as-root", Container: c.Name, Message: "may run as root, neither runAsNonRoot nor runAsUser is set"}}
+	}
+	return nil
+}
+
+// Returns true if the pod template can only be scheduled on spot nodes
+func isSpotOnly(spec v1.PodSpec) bool {
+	if v, ok := spec.NodeSelector[node.Labels.CapacityType]; ok && node.NormalizeCapacityType(v) == node.Spot {
+		return true
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil || spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return false
+	}
+	// every term has to restrict capacity type to spot
+	for _, term := range terms {
+		spot := false
+		for _, e := range term.MatchExpressions {
+			if e.Key == node.Labels.CapacityType && e.Operator == v1.NodeSelectorOpIn && len(e.Values) == 1 && node.NormalizeCapacityType(e.Values[0]) == node.Spot {
+				spot = true
+			}
+		}
+		if !spot {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAntiAffinity(spec v1.PodSpec) bool {
+	if len(spec.TopologySpreadConstraints) != 0 {
+		return true
+	}
+	return spec.Affinity != nil && spec.Affinity.PodAntiAffinity != nil &&
+		(len(spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) != 0 ||
+			len(spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 0)
+}
+
+// Audit a single deployment against the PDBs of the cluster
+func AuditDeployment(d appsv1.Deployment, pdbs []policyv1.PodDisruptionBudget) []Finding {
+	var findings []Finding
+	spec := d.Spec.Template.Spec
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if replicas == 1 && len(pdb.FindForPods(pdbs, d.Namespace, d.Spec.Template.Labels)) == 0 {
+		findings = append(findings, Finding{Severity: SeverityMedium, Check: "single-replica-no-pdb", Message: "single replica without a PodDisruptionBudget"})
+	}
+	if isSpotOnly(spec) && !hasAntiAffinity(spec) {
+		findings = append(findings, Finding{Severity: SeverityMedium, Check: "spot-no-anti-affinity", Message: "spot only workload without pod anti-affinity or topology spread"})
+	}
+
+	for _, c := range spec.InitContainers {
+		findings = append(findings, checkImage(c)...)
+		findings = append(findings, checkRunAsRoot(spec, c)...)
+		if pod.IsSidecar(c) {
+			findings = append(findings, checkResources(c)...)
+		}
+	}
+	for _, c := range spec.Containers {
+		findings = append(findings, checkResources(c)...)
+		findings = append(findings, checkImage(c)...)
+		findings = append(findings, checkProbes(c)...)
+		findings = append(findings, checkRunAsRoot(spec, c)...)
+	}
+
+	for i := range findings {
+		findings[i].Namespace = d.Namespace
+		findings[i].Deployment = d.Name
+	}
+	return findings
+}
+
+// Audit all deployments of the namespace
+func GetFindings(namespace string) ([]Finding, error) {
+	deployList, err := deployment.GetDeployments(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	pdbList, err := pdb.GetPodDisruptionBudgets(&namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, d := range deployList.Items {
+		findings = append(findings, AuditDeployment(d, pdbList.Items)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if severityRank[findings[i].Severity] != severityRank[findings[j].Severity] {
+			return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+		}
+		if findings[i].Namespace != findings[j].Namespace {
+			return findings[i].Namespace < findings[j].Namespace
+		}
+		return findings[i].Deployment < findings[j].Deployment
+	})
+	return findings, nil
+}
+
+// Returns true if any finding is at or above the given severity, "none" never fails
+func Failed(findings []Finding, failOn string) bool {
+	threshold, ok := severityRank[strings.ToUpper(failOn)]
+	if !ok {
+		return false
+	}
+	for _, f := range findings {
+		if severityRank[f.Severity] >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Print findings as a table or as json
+func PrintFindings(findings []Finding, output string) error {
+	if output == "json" {
+		if findings == nil {
+			findings = []Finding{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(findings)
+	}
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "SEVERITY\t\tCHECK\t\tNAMESPACE\t\tDEPLOYMENT\t\tCONTAINER\t\tMESSAGE")
+	for _, f := range findings {
+		container := f.Container
+		if container == "" {
+			container = "-"
+		}
+		data := f.Severity + "\t\t" + f.Check + "\t\t" + f.Namespace + "\t\t" + f.Deployment + "\t\t" + container + "\t\t" + f.Message
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
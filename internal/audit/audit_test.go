@@ -0,0 +1,134 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"sort"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		image  string
+		tag    string
+		pinned bool
+	}{
+		{"nginx", "", false},
+		{"nginx:latest", "latest", false},
+		{"nginx:1.25", "1.25", false},
+		{"registry.local:5000/team/app", "", false},
+		{"registry.local:5000/team/app:v2", "v2", false},
+		{"nginx@sha256:abcd", "", true},
+		{"nginx:1.25@sha256:abcd", "1.25", true},
+	}
+	for _, tt := range tests {
+		tag, pinned := parseImage(tt.image)
+		if tag != tt.tag || pinned != tt.pinned {
+			t.Errorf("parseImage(%q) = %q, %v, want %q, %v", tt.image, tag, pinned, tt.tag, tt.pinned)
+		}
+	}
+}
+
+func checks(findings []Finding) []string {
+	var c []string
+	for _, f := range findings {
+		c = append(c, f.Check)
+	}
+	sort.Strings(c)
+	return c
+}
+
+func TestAuditDeployment(t *testing.T) {
+	one := int32(1)
+	nonRoot := true
+	labels := map[string]string{"app": "web"}
+	resources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("128Mi")},
+		Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("200m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+	}
+
+	healthy := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &one,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					SecurityContext: &v1.PodSecurityContext{RunAsNonRoot: &nonRoot},
+					Containers: []v1.Container{{
+						Name:           "web",
+						Image:          "nginx:1.25",
+						Resources:      resources,
+						ReadinessProbe: &v1.Probe{},
+						LivenessProbe:  &v1.Probe{},
+					}},
+				},
+			},
+		},
+	}
+	pdbs := []policyv1.PodDisruptionBudget{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}}
+
+	if got := AuditDeployment(healthy, pdbs); len(got) != 0 {
+		t.Errorf("AuditDeployment(healthy) = %v, want no findings", checks(got))
+	}
+
+	bad := *healthy.DeepCopy()
+	bad.Spec.Template.Spec.SecurityContext = nil
+	bad.Spec.Template.Spec.NodeSelector = map[string]string{"eks.amazonaws.com/capacityType": "SPOT"}
+	bad.Spec.Template.Spec.Containers[0].Image = "nginx"
+	bad.Spec.Template.Spec.Containers[0].Resources = v1.ResourceRequirements{}
+	bad.Spec.Template.Spec.Containers[0].ReadinessProbe = nil
+	bad.Spec.Template.Spec.Containers = append(bad.Spec.Template.Spec.Containers, v1.Container{
+		Name:            "proxy",
+		Image:           "envoy@sha256:abcd",
+		ImagePullPolicy: v1.PullAlways,
+		Resources:       resources,
+		ReadinessProbe:  &v1.Probe{},
+		LivenessProbe:   &v1.Probe{},
+		SecurityContext: &v1.SecurityContext{RunAsNonRoot: &nonRoot},
+	})
+
+	want := []string{"image-tag", "missing-limits", "missing-requests", "pull-always-digest", "readiness-probe", "run-as-root", "single-replica-no-pdb", "spot-no-anti-affinity"}
+	got := checks(AuditDeployment(bad, nil))
+	if len(got) != len(want) {
+		t.Fatalf("AuditDeployment(bad) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("AuditDeployment(bad) = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFailed(t *testing.T) {
+	findings := []Finding{{Severity: SeverityMedium}}
+	if !Failed(findings, "medium") || !Failed(findings, "low") {
+		t.Error("expected failure at or below MEDIUM")
+	}
+	if Failed(findings, "high") || Failed(findings, "none") {
+		t.Error("expected no failure above MEDIUM or with none")
+	}
+}
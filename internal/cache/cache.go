@@ -0,0 +1,102 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	mu     sync.Mutex
+	source Source
+)
+
+/*
+Source reads the objects kshow works with,
+either straight from the API server or from informer caches. An empty namespace means all namespaces
+*/
+type Source interface {
+	Nodes() ([]v1.Node, error)
+	Pods(namespace string) ([]v1.Pod, error)
+	Deployments(namespace string) ([]appsv1.Deployment, error)
+	ReplicaSets(namespace string) ([]appsv1.ReplicaSet, error)
+	PodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error)
+	HorizontalPodAutoscalers(namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error)
+	ResourceQuotas(namespace string) ([]v1.ResourceQuota, error)
+	LimitRanges(namespace string) ([]v1.LimitRange, error)
+}
+
+// Use a source for every following read, e.g. a synced Informer
+func SetSource(s Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	source = s
+}
+
+// Get the current source, defaults to direct API calls
+func GetSource() (Source, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if source == nil {
+		clientset, err := k8sclient.GetK8sClient()
+		if err != nil {
+			return nil, err
+		}
+		source = NewDirect(clientset)
+	}
+	return source, nil
+}
+
+/*
+Get a source reading through clientset,
+the informer caches if they were started for the same clientset, direct API calls otherwise
+*/
+func SourceFor(clientset kubernetes.Interface) Source {
+	mu.Lock()
+	defer mu.Unlock()
+	if informer, ok := source.(*Informer); ok && informer.clientset == clientset {
+		return informer
+	}
+	return NewDirect(clientset)
+}
+
+/*
+Read from informer caches for the rest of the process,
+blocks until the caches of namespace are synced or timeout expires
+*/
+func UseInformers(namespace string, timeout time.Duration) error {
+	clientset, err := k8sclient.GetK8sClient()
+	if err != nil {
+		return err
+	}
+	informer := NewInformer(clientset, namespace, 0)
+	informer.Start(make(chan struct{}))
+	if err := informer.WaitForSync(timeout); err != nil {
+		return err
+	}
+	SetSource(informer)
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testObjects() []runtime.Object {
+	return []runtime.Object{
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "edge", Name: "proxy"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-b"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-a"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web"}},
+	}
+}
+
+func podNames(pods []v1.Pod) []string {
+	var names []string
+	for _, p := range pods {
+		names = append(names, p.Namespace+"/"+p.Name)
+	}
+	return names
+}
+
+func startInformer(t *testing.T, namespace string) *Informer {
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	informer := NewInformer(fake.NewSimpleClientset(testObjects()...), namespace, 0)
+	informer.Start(stop)
+	if err := informer.WaitForSync(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return informer
+}
+
+// Both sources return the same objects in the same order
+func TestSources(t *testing.T) {
+	sources := map[string]Source{
+		"direct":   NewDirect(fake.NewSimpleClientset(testObjects()...)),
+		"informer": startInformer(t, ""),
+	}
+	for name, s := range sources {
+		pods, err := s.Pods("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := podNames(pods), []string{"app/web-a", "app/web-b", "edge/proxy"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Pods() = %v, want %v", name, got, want)
+		}
+		pods, err = s.Pods("app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := podNames(pods), []string{"app/web-a", "app/web-b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Pods(app) = %v, want %v", name, got, want)
+		}
+		nodes, err := s.Nodes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 2 || nodes[0].Name != "node-1" {
+			t.Errorf("%s: Nodes() = %v, want node-1, node-2", name, nodes)
+		}
+		deployments, err := s.Deployments("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deployments) != 1 {
+			t.Errorf("%s: Deployments() returned %d, want 1", name, len(deployments))
+		}
+	}
+}
+
+func TestInformerNamespaceScope(t *testing.T) {
+	informer := startInformer(t, "app")
+
+	pods, err := informer.Pods("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := podNames(pods), []string{"app/web-a", "app/web-b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pods() = %v, want %v", got, want)
+	}
+	if _, err := informer.Pods("edge"); err == nil {
+		t.Errorf("Pods(edge) outside the cached namespace returned no error")
+	}
+	// nodes are cluster scoped and cached regardless of the namespace
+	nodes, err := informer.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("Nodes() returned %d nodes, want 2", len(nodes))
+	}
+}
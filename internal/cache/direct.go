@@ -0,0 +1,134 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/pager"
+)
+
+// Direct reads every object from the API server on each call
+type Direct struct {
+	clientset kubernetes.Interface
+}
+
+func NewDirect(clientset kubernetes.Interface) *Direct {
+	return &Direct{clientset: clientset}
+}
+
+/*
+List in chunks of k8sclient.ListChunkSize,
+falls back to a full list if the continue token expires between chunks
+*/
+func list(page func(opts metav1.ListOptions) (runtime.Object, error), each func(obj runtime.Object)) error {
+	p := pager.New(pager.SimplePageFunc(page))
+	p.PageSize = k8sclient.ListChunkSize
+	return p.EachListItem(context.TODO(), metav1.ListOptions{}, func(obj runtime.Object) error {
+		each(obj)
+		return nil
+	})
+}
+
+func (d *Direct) Nodes() ([]v1.Node, error) {
+	var items []v1.Node
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.CoreV1().Nodes().List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*v1.Node))
+	})
+	return items, err
+}
+
+func (d *Direct) Pods(namespace string) ([]v1.Pod, error) {
+	var items []v1.Pod
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.CoreV1().Pods(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*v1.Pod))
+	})
+	return items, err
+}
+
+func (d *Direct) Deployments(namespace string) ([]appsv1.Deployment, error) {
+	var items []appsv1.Deployment
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.AppsV1().Deployments(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*appsv1.Deployment))
+	})
+	return items, err
+}
+
+func (d *Direct) ReplicaSets(namespace string) ([]appsv1.ReplicaSet, error) {
+	var items []appsv1.ReplicaSet
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.AppsV1().ReplicaSets(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*appsv1.ReplicaSet))
+	})
+	return items, err
+}
+
+func (d *Direct) PodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	var items []policyv1.PodDisruptionBudget
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*policyv1.PodDisruptionBudget))
+	})
+	return items, err
+}
+
+func (d *Direct) HorizontalPodAutoscalers(namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	var items []autoscalingv2.HorizontalPodAutoscaler
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*autoscalingv2.HorizontalPodAutoscaler))
+	})
+	return items, err
+}
+
+func (d *Direct) ResourceQuotas(namespace string) ([]v1.ResourceQuota, error) {
+	var items []v1.ResourceQuota
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.CoreV1().ResourceQuotas(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*v1.ResourceQuota))
+	})
+	return items, err
+}
+
+func (d *Direct) LimitRanges(namespace string) ([]v1.LimitRange, error) {
+	var items []v1.LimitRange
+	err := list(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return d.clientset.CoreV1().LimitRanges(namespace).List(context.TODO(), opts)
+	}, func(obj runtime.Object) {
+		items = append(items, *obj.(*v1.LimitRange))
+	})
+	return items, err
+}
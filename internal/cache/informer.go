@@ -0,0 +1,239 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	autoscalinglisters "k8s.io/client-go/listers/autoscaling/v2"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+)
+
+/*
+Informer serves reads from shared informer caches,
+the caches are filled by one list and kept up to date with watches. Pod metrics
+have no watch and are always read from the metrics API
+*/
+type Informer struct {
+	// nodes are cluster scoped and need their own factory when namespace is set
+	factories []informers.SharedInformerFactory
+	clientset kubernetes.Interface
+	namespace string
+
+	nodes       corelisters.NodeLister
+	pods        corelisters.PodLister
+	deployments appslisters.DeploymentLister
+	replicaSets appslisters.ReplicaSetLister
+	pdbs        policylisters.PodDisruptionBudgetLister
+	hpas        autoscalinglisters.HorizontalPodAutoscalerLister
+	quotas      corelisters.ResourceQuotaLister
+	limitRanges corelisters.LimitRangeLister
+}
+
+/*
+Create informers for every kind kshow reads,
+namespace scopes the namespaced kinds, empty for all namespaces
+*/
+func NewInformer(clientset kubernetes.Interface, namespace string, resync time.Duration) *Informer {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, resync, informers.WithNamespace(namespace))
+	nodeFactory := factory
+	factories := []informers.SharedInformerFactory{factory}
+	if namespace != "" {
+		nodeFactory = informers.NewSharedInformerFactory(clientset, resync)
+		factories = append(factories, nodeFactory)
+	}
+	// requesting a lister registers its informer with the factory before Start
+	return &Informer{
+		factories:   factories,
+		clientset:   clientset,
+		namespace:   namespace,
+		nodes:       nodeFactory.Core().V1().Nodes().Lister(),
+		pods:        factory.Core().V1().Pods().Lister(),
+		deployments: factory.Apps().V1().Deployments().Lister(),
+		replicaSets: factory.Apps().V1().ReplicaSets().Lister(),
+		pdbs:        factory.Policy().V1().PodDisruptionBudgets().Lister(),
+		hpas:        factory.Autoscaling().V2().HorizontalPodAutoscalers().Lister(),
+		quotas:      factory.Core().V1().ResourceQuotas().Lister(),
+		limitRanges: factory.Core().V1().LimitRanges().Lister(),
+	}
+}
+
+// Start the informers, they run until stop is closed
+func (i *Informer) Start(stop <-chan struct{}) {
+	for _, f := range i.factories {
+		f.Start(stop)
+	}
+}
+
+// Wait until every cache is synced, or return an error after timeout
+func (i *Informer) WaitForSync(timeout time.Duration) error {
+	expired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+
+	for _, f := range i.factories {
+		for kind, synced := range f.WaitForCacheSync(expired) {
+			if !synced {
+				return fmt.Errorf("cache of %v not synced within %v", kind, timeout)
+			}
+		}
+	}
+	return nil
+}
+
+// Reads outside the namespace the informers were started for are an error
+func (i *Informer) scope(namespace string) (string, error) {
+	if i.namespace == "" {
+		return namespace, nil
+	}
+	if namespace != "" && namespace != i.namespace {
+		return "", errors.New("cache only holds namespace " + i.namespace + ", not " + namespace)
+	}
+	return i.namespace, nil
+}
+
+// Sort by namespace and name, the order of a list from the API server
+func sortObjects[T any](items []T, meta func(*T) *metav1.ObjectMeta) {
+	sort.Slice(items, func(a, b int) bool {
+		ma, mb := meta(&items[a]), meta(&items[b])
+		if ma.Namespace != mb.Namespace {
+			return ma.Namespace < mb.Namespace
+		}
+		return ma.Name < mb.Name
+	})
+}
+
+func (i *Informer) Nodes() ([]v1.Node, error) {
+	list, err := i.nodes.List(labels.Everything())
+	items := make([]v1.Node, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *v1.Node) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) Pods(namespace string) ([]v1.Pod, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.pods.Pods(namespace).List(labels.Everything())
+	items := make([]v1.Pod, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *v1.Pod) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) Deployments(namespace string) ([]appsv1.Deployment, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.deployments.Deployments(namespace).List(labels.Everything())
+	items := make([]appsv1.Deployment, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *appsv1.Deployment) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) ReplicaSets(namespace string) ([]appsv1.ReplicaSet, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.replicaSets.ReplicaSets(namespace).List(labels.Everything())
+	items := make([]appsv1.ReplicaSet, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *appsv1.ReplicaSet) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) PodDisruptionBudgets(namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.pdbs.PodDisruptionBudgets(namespace).List(labels.Everything())
+	items := make([]policyv1.PodDisruptionBudget, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *policyv1.PodDisruptionBudget) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) HorizontalPodAutoscalers(namespace string) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.hpas.HorizontalPodAutoscalers(namespace).List(labels.Everything())
+	items := make([]autoscalingv2.HorizontalPodAutoscaler, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *autoscalingv2.HorizontalPodAutoscaler) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) ResourceQuotas(namespace string) ([]v1.ResourceQuota, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.quotas.ResourceQuotas(namespace).List(labels.Everything())
+	items := make([]v1.ResourceQuota, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *v1.ResourceQuota) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
+
+func (i *Informer) LimitRanges(namespace string) ([]v1.LimitRange, error) {
+	namespace, err := i.scope(namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := i.limitRanges.LimitRanges(namespace).List(labels.Everything())
+	items := make([]v1.LimitRange, 0, len(list))
+	for _, o := range list {
+		items = append(items, *o)
+	}
+	sortObjects(items, func(o *v1.LimitRange) *metav1.ObjectMeta { return &o.ObjectMeta })
+	return items, err
+}
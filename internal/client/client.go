@@ -17,58 +17,113 @@ limitations under the License.
 package client
 
 import (
-	"os"
-	"path/filepath"
+	"fmt"
+	"sync"
 
-	"go.uber.org/zap"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 var (
-	logger *zap.Logger
+	mu               sync.Mutex
+	clientset        *kubernetes.Clientset
+	metricsClientset *metricsv.Clientset
 )
 
-func init() {
-	logger, _ = zap.NewProduction()
+// Number of objects fetched per list call, large clusters are listed in chunks with Limit/Continue
+const ListChunkSize = 500
 
+func inCluster() (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("no kubeconfig found and not running in a cluster: %w", err)
+	}
+	return config, nil
 }
 
-func inCluster() (*kubernetes.Clientset, error) {
-	config, err := rest.InClusterConfig()
+// Options override the kubeconfig like the kubectl flags of the same name
+type Options struct {
+	Kubeconfig, Context, As string
+	// RequestTimeout is a duration like 30s, 0 waits forever
+	RequestTimeout string
+}
+
+var options Options
+
+// Set the kubeconfig overrides, before the first clientset is created
+func Configure(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	options = o
+}
+
+/*
+Get the client config the way kubectl loads it: --kubeconfig, $KUBECONFIG or
+~/.kube/config, with --context, --as and --request-timeout applied
+*/
+func clientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = options.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: options.Context, Timeout: options.RequestTimeout}
+	overrides.AuthInfo.Impersonate = options.As
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
+
+// Get the rest config from the kubeconfig, or the in-cluster config if there is none
+func getConfig() (*rest.Config, error) {
+	config, err := clientConfig().ClientConfig()
+	if clientcmd.IsEmptyConfig(err) {
+		return inCluster()
+	}
+	return config, err
+}
+
+// Get the name of the current kubeconfig context, empty in a cluster or without a kubeconfig
+func CurrentContext() string {
+	if options.Context != "" {
+		return options.Context
+	}
+	config, err := clientConfig().RawConfig()
 	if err != nil {
-		panic(err.Error())
+		return ""
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	return clientset, err
+	return config.CurrentContext
 }
 
-func outCluster(kubeconfig string) (*kubernetes.Clientset, error) {
-	config, _ := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	// creates the clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	return clientset, err
+// Get the namespace of the current context, default if it has none
+func ContextNamespace() (string, error) {
+	namespace, _, err := clientConfig().Namespace()
+	return namespace, err
 }
 
+// Get the clientset, it is created once and shared by the whole process
 func GetK8sClient() (*kubernetes.Clientset, error) {
-	var (
-		kubeconfig string
-		client     *kubernetes.Clientset
-		err        error
-	)
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
+	mu.Lock()
+	defer mu.Unlock()
+	if clientset != nil {
+		return clientset, nil
 	}
-	_, err = os.Stat(kubeconfig)
+	config, err := getConfig()
 	if err != nil {
-		logger.Error(err.Error())
+		return nil, err
+	}
+	clientset, err = kubernetes.NewForConfig(config)
+	return clientset, err
+}
+
+// Get the metrics-server clientset, it is created once and shared by the whole process
+func GetMetricsClient() (*metricsv.Clientset, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if metricsClientset != nil {
+		return metricsClientset, nil
 	}
-	if os.IsNotExist(err) {
-		client, err = inCluster()
-	} else {
-		client, err = outCluster(kubeconfig)
+	config, err := getConfig()
+	if err != nil {
+		return nil, err
 	}
-	return client, err
+	metricsClientset, err = metricsv.NewForConfig(config)
+	return metricsClientset, err
 }
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package columns prints tables from column specs like kubectl custom-columns,
+NAME:.metadata.name,ZONE:node.zone. A field starting with a dot is a jsonpath
+into the raw object, any other field is one derived by kshow, e.g. tenancy
+*/
+package columns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Shown for a raw field missing on the object
+const none = "<none>"
+
+// Column is a header with the field printed under it
+type Column struct {
+	Header, Field string
+}
+
+// Raw is true if the field is a jsonpath into the object
+func (c Column) Raw() bool {
+	return strings.HasPrefix(c.Field, ".") || strings.HasPrefix(c.Field, "{")
+}
+
+// Parse a spec of comma separated HEADER:field columns
+func Parse(spec string) ([]Column, error) {
+	var cols []Column
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		i := strings.Index(part, ":")
+		if i <= 0 || i == len(part)-1 {
+			return nil, errors.New("column " + part + " is not HEADER:field")
+		}
+		cols = append(cols, Column{Header: part[:i], Field: part[i+1:]})
+	}
+	if len(cols) == 0 {
+		return nil, errors.New("no columns in " + spec)
+	}
+	return cols, nil
+}
+
+// Fields derived by kshow for rows of type T, by field name
+type Fields[T any] map[string]func(T) string
+
+// Names of the fields, sorted
+func (f Fields[T]) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Uses is true if any column prints one of the fields
+func Uses(cols []Column, fields ...string) bool {
+	for _, c := range cols {
+		for _, f := range fields {
+			if c.Field == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A column compiled against the fields of T
+type compiled[T any] struct {
+	derived func(T) string
+	path    *jsonpath.JSONPath
+}
+
+func compile[T any](cols []Column, fields Fields[T]) ([]compiled[T], error) {
+	out := make([]compiled[T], len(cols))
+	for i, c := range cols {
+		if !c.Raw() {
+			f, ok := fields[c.Field]
+			if !ok {
+				return nil, errors.New("unknown field " + c.Field + ", use a .jsonpath or one of: " + strings.Join(fields.Names(), ", "))
+			}
+			out[i].derived = f
+			continue
+		}
+		path := c.Field
+		if !strings.HasPrefix(path, "{") {
+			path = "{" + path + "}"
+		}
+		jp := jsonpath.New(c.Header).AllowMissingKeys(true)
+		if err := jp.Parse(path); err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.Header, err)
+		}
+		out[i].path = jp
+	}
+	return out, nil
+}
+
+/*
+Print rows as a table of the columns,
+object returns the raw object of a row for jsonpath fields
+*/
+func Print[T any](w io.Writer, cols []Column, rows []T, fields Fields[T], object func(T) runtime.Object) error {
+	compiled, err := compile(cols, fields)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 1, 1, 1, ' ', 0)
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = c.Header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t\t"))
+
+	for _, row := range rows {
+		var raw map[string]interface{}
+		cells := make([]string, len(compiled))
+		for i, c := range compiled {
+			if c.derived != nil {
+				cells[i] = c.derived(row)
+				continue
+			}
+			if raw == nil {
+				if raw, err = runtime.DefaultUnstructuredConverter.ToUnstructured(object(row)); err != nil {
+					return err
+				}
+			}
+			var buf bytes.Buffer
+			if err := c.path.Execute(&buf, raw); err != nil {
+				return fmt.Errorf("column %s: %w", cols[i].Header, err)
+			}
+			cells[i] = buf.String()
+			if cells[i] == "" {
+				cells[i] = none
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package columns
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Column
+		wantErr bool
+	}{
+		{"NAME:.metadata.name,ZONE:node.zone", []Column{{"NAME", ".metadata.name"}, {"ZONE", "node.zone"}}, false},
+		{" NAME:name , AGE:age,", []Column{{"NAME", "name"}, {"AGE", "age"}}, false},
+		{"IMAGE:{.spec.containers[0].image}", []Column{{"IMAGE", "{.spec.containers[0].image}"}}, false},
+		{"NAME", nil, true},
+		{":name", nil, true},
+		{"NAME:", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+type testRow struct {
+	pod     *v1.Pod
+	tenancy string
+}
+
+var testFields = Fields[testRow]{
+	"tenancy": func(r testRow) string { return r.tenancy },
+}
+
+func testObject(r testRow) runtime.Object {
+	return r.pod
+}
+
+func TestPrint(t *testing.T) {
+	rows := []testRow{
+		{&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-0", Labels: map[string]string{"team": "web"}}}, "SPOT"},
+		{&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1"}}, "ON_DEMAND"},
+	}
+	cols, err := Parse("NAME:.metadata.name,TEAM:.metadata.labels.team,TENANCY:tenancy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := Print(&out, cols, rows, testFields, testObject); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := [][]string{{"NAME", "TEAM", "TENANCY"}, {"app-0", "web", "SPOT"}, {"app-1", "<none>", "ON_DEMAND"}}
+	if len(lines) != len(want) {
+		t.Fatalf("Print() printed %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if got := strings.Fields(line); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("line %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestPrintUnknownField(t *testing.T) {
+	cols := []Column{{"ZONE", "node.zone"}}
+	err := Print(&bytes.Buffer{}, cols, []testRow{}, testFields, testObject)
+	if err == nil || !strings.Contains(err.Error(), "tenancy") {
+		t.Errorf("Print() error = %v, want the known fields listed", err)
+	}
+}
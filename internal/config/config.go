@@ -0,0 +1,192 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+Config is the kshow config file:
+
+	namespaces:
+	  prod-cluster: app-server
+	output: custom-columns=NAME:.metadata.name,NODE:node
+	labels:
+	  capacityType: karpenter.sh/capacity-type
+	  capacityTypeValues:
+	    on-demand: ON_DEMAND
+	    spot: SPOT
+	columns:
+	  pods:
+	    zones: NAME:.metadata.name,NODE:node,ZONE:node.zone
+	aliases:
+	  spot: get pods --detailed --columns usage
+*/
+type Config struct {
+	// Default namespace per kubeconfig context
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+	// Default output format of get
+	Output string `json:"output,omitempty"`
+	// Node labels of the provider, EKS labels are used for the ones not set
+	Labels Labels `json:"labels,omitempty"`
+	// Column presets per kind (pods, nodes, deployments) by preset name
+	Columns map[string]map[string]string `json:"columns,omitempty"`
+	// Command aliases, the alias is replaced by the words of its command
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Labels are the node labels placement is read from
+type Labels struct {
+	NodeGroup    string `json:"nodeGroup,omitempty"`
+	CapacityType string `json:"capacityType,omitempty"`
+	InstanceType string `json:"instanceType,omitempty"`
+	Zone         string `json:"zone,omitempty"`
+	// Values of the capacity type label mapped to ON_DEMAND or SPOT
+	CapacityTypeValues map[string]string `json:"capacityTypeValues,omitempty"`
+}
+
+// Get the path of the config file, ~/.config/kshow/config.yaml
+func Path() string {
+	return filepath.Join(homedir.HomeDir(), ".config", "kshow", "config.yaml")
+}
+
+// Load the config file, an empty config if there is none
+func Load() (*Config, error) {
+	return load(Path(), true)
+}
+
+/*
+Load the config file ignoring unknown keys, for config set to fix
+a file Load rejects. Saving it drops the unknown keys
+*/
+func LoadLenient() (*Config, error) {
+	return load(Path(), false)
+}
+
+func load(path string, strict bool) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	unmarshal := yaml.Unmarshal
+	if strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+	if err := unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save the config file, creating its directory
+func (c *Config) Save() error {
+	return c.save(Path())
+}
+
+func (c *Config) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Get a column preset of a kind, ok is false if the config has none with that name
+func (c *Config) ColumnPreset(kind, name string) (string, bool) {
+	spec, ok := c.Columns[kind][name]
+	return spec, ok
+}
+
+// Set a value in a map, an empty value removes the key
+func setEntry(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		return m
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
+
+/*
+Set a key of the config, an empty value unsets it. Keys are output,
+namespaces.<context>, labels.<nodeGroup|capacityType|instanceType|zone>,
+labels.capacityTypeValues.<value>, columns.<kind>.<preset> and aliases.<name>
+*/
+func (c *Config) Set(key, value string) error {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) == 1 {
+		if key != "output" {
+			return errors.New("unknown config key " + key)
+		}
+		c.Output = value
+		return nil
+	}
+	switch section, rest := parts[0], parts[1]; section {
+	case "namespaces":
+		c.Namespaces = setEntry(c.Namespaces, rest, value)
+	case "aliases":
+		c.Aliases = setEntry(c.Aliases, rest, value)
+	case "columns":
+		kind, preset, ok := strings.Cut(rest, ".")
+		if !ok {
+			return errors.New("config key " + key + " is not columns.<kind>.<preset>")
+		}
+		if c.Columns == nil {
+			c.Columns = make(map[string]map[string]string)
+		}
+		c.Columns[kind] = setEntry(c.Columns[kind], preset, value)
+		if len(c.Columns[kind]) == 0 {
+			delete(c.Columns, kind)
+		}
+	case "labels":
+		switch {
+		case rest == "nodeGroup":
+			c.Labels.NodeGroup = value
+		case rest == "capacityType":
+			c.Labels.CapacityType = value
+		case rest == "instanceType":
+			c.Labels.InstanceType = value
+		case rest == "zone":
+			c.Labels.Zone = value
+		case strings.HasPrefix(rest, "capacityTypeValues."):
+			c.Labels.CapacityTypeValues = setEntry(c.Labels.CapacityTypeValues, strings.TrimPrefix(rest, "capacityTypeValues."), value)
+		default:
+			return errors.New("unknown config key " + key)
+		}
+	default:
+		return errors.New("unknown config key " + key)
+	}
+	return nil
+}
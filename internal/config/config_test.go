@@ -0,0 +1,109 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := load(filepath.Join(dir, "missing.yaml"), true)
+	if err != nil || cfg == nil {
+		t.Fatalf("load() of a missing file = %v, %v, want an empty config", cfg, err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	data := "columns:\n  pods:\n    zones: NAME:.metadata.name,ZONE:node.zone\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec, ok := cfg.ColumnPreset("pods", "zones"); !ok || spec != "NAME:.metadata.name,ZONE:node.zone" {
+		t.Errorf("ColumnPreset(pods, zones) = %q, %v", spec, ok)
+	}
+	if _, ok := cfg.ColumnPreset("nodes", "zones"); ok {
+		t.Errorf("ColumnPreset(nodes, zones) found a preset of pods")
+	}
+
+	if err := os.WriteFile(path, []byte("colums: {}\noutput: wide\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(path, true); err == nil {
+		t.Errorf("load() accepted an unknown key")
+	}
+	// config set loads leniently to fix the file
+	if cfg, err := load(path, false); err != nil || cfg.Output != "wide" {
+		t.Errorf("lenient load() = %+v, %v, want the known keys", cfg, err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := &Config{}
+	sets := [][2]string{
+		{"output", "custom-columns=NAME:name"},
+		{"namespaces.arn:aws:eks:eu-west-1:1:cluster/prod", "app-server"},
+		{"labels.capacityType", "karpenter.sh/capacity-type"},
+		{"labels.capacityTypeValues.on-demand", "ON_DEMAND"},
+		{"columns.pods.zones", "NAME:name,ZONE:node.zone"},
+		{"aliases.spot", "get pods --detailed"},
+		{"aliases.old", "get nodes"},
+		{"aliases.old", ""},
+	}
+	for _, kv := range sets {
+		if err := cfg.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q) error = %v", kv[0], err)
+		}
+	}
+	if cfg.Output != "custom-columns=NAME:name" || cfg.Namespaces["arn:aws:eks:eu-west-1:1:cluster/prod"] != "app-server" {
+		t.Errorf("Set() = %+v", cfg)
+	}
+	if cfg.Labels.CapacityType != "karpenter.sh/capacity-type" || cfg.Labels.CapacityTypeValues["on-demand"] != "ON_DEMAND" {
+		t.Errorf("Set() labels = %+v", cfg.Labels)
+	}
+	if _, ok := cfg.ColumnPreset("pods", "zones"); !ok {
+		t.Errorf("Set() did not add the column preset")
+	}
+	if _, ok := cfg.Aliases["old"]; ok || cfg.Aliases["spot"] != "get pods --detailed" {
+		t.Errorf("Set() aliases = %v", cfg.Aliases)
+	}
+
+	for _, key := range []string{"namespace", "labels.region", "columns.pods", "colors.status"} {
+		if err := cfg.Set(key, "x"); err == nil {
+			t.Errorf("Set(%q) accepted an unknown key", key)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "kshow", "config.yaml")
+	if err := cfg.save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("load() after save() = %+v, want %+v", loaded, cfg)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/metrics"
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	lineBreaker string
+)
+
+// Hours used for monthly estimates
+const hoursPerMonth = 730
+
+func init() {
+	lineBreaker = "--------------------------------------------------------------------------------------------------------"
+
+}
+
+/*
+PriceTable is the hourly price per instance type and capacity type,
+loaded from a yaml or json file:
+
+	m5.xlarge:
+	  ON_DEMAND: 0.192
+	  SPOT: 0.0672
+*/
+type PriceTable map[string]map[string]float64
+
+// Usage is the current cpu (cores) and memory (bytes) usage of a pod
+type Usage struct {
+	CPU, Mem float64
+}
+
+// PodCost is the share of its node's hourly cost apportioned to a pod
+type PodCost struct {
+	Namespace, Pod, Deployment, Team, Node string
+	Hourly                                 float64
+}
+
+// Load the price table from a file
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	prices := PriceTable{}
+	if err := yaml.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("parsing price table %s: %w", path, err)
+	}
+	return prices, nil
+}
+
+// Get the hourly price of a node from its instance type and capacity type labels
+func (p PriceTable) NodePrice(n v1.Node) (float64, bool) {
+	instanceType := node.GetInstanceType(n)
+	capacityType := node.GetCapacityType(n)
+	if capacityType == "" {
+		capacityType = node.OnDemand
+	}
+	price, ok := p[instanceType][capacityType]
+	return price, ok
+}
+
+func usageKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+/*
+Apportion node costs to pods,
+a pod's share is the average of its cpu and memory fraction of the node's allocatable.
+Requests are used when usage is nil. Capacity not claimed by any pod stays unallocated.
+deployments maps namespace/pod to its deployment, see deployment.GetPodDeployments.
+Returns the nodes whose price is missing from the table
+*/
+func Allocate(nodes []v1.Node, pods []v1.Pod, deployments map[string]string, usage map[string]Usage, prices PriceTable, teamLabel string) ([]PodCost, []string) {
+	type nodeInfo struct {
+		hourly, cpu, mem float64
+	}
+	nodeInfos := make(map[string]nodeInfo)
+	var missing []string
+	for _, n := range nodes {
+		price, ok := prices.NodePrice(n)
+		if !ok {
+			missing = append(missing, n.Name)
+		}
+		nodeInfos[n.Name] = nodeInfo{
+			hourly: price,
+			cpu:    n.Status.Allocatable.Cpu().AsApproximateFloat64(),
+			mem:    n.Status.Allocatable.Memory().AsApproximateFloat64(),
+		}
+	}
+
+	var costs []PodCost
+	for _, p := range pods {
+		if p.Spec.NodeName == "" || p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		n, ok := nodeInfos[p.Spec.NodeName]
+		if !ok || n.cpu == 0 || n.mem == 0 {
+			continue
+		}
+
+		var cpu, mem float64
+		if usage != nil {
+			u := usage[usageKey(p.Namespace, p.Name)]
+			cpu, mem = u.CPU, u.Mem
+		} else {
+			requests := pod.GetPodRequests(p.Spec)
+			cpu = requests.Cpu().AsApproximateFloat64()
+			mem = requests.Memory().AsApproximateFloat64()
+		}
+		share := (cpu/n.cpu + mem/n.mem) / 2
+
+		costs = append(costs, PodCost{
+			Namespace:  p.Namespace,
+			Pod:        p.Name,
+			Deployment: deployments[usageKey(p.Namespace, p.Name)],
+			Team:       p.Labels[teamLabel],
+			Node:       p.Spec.NodeName,
+			Hourly:     share * n.hourly,
+		})
+	}
+	return costs, missing
+}
+
+// Get current usage of pods from metrics-server
+func getUsage(namespace string) (map[string]Usage, error) {
+	usage := make(map[string]Usage)
+	podMetrics, err := metrics.GetPodMetrics(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range podMetrics.Items {
+		var u Usage
+		for _, c := range m.Containers {
+			u.CPU += c.Usage.Cpu().AsApproximateFloat64()
+			u.Mem += c.Usage.Memory().AsApproximateFloat64()
+		}
+		usage[usageKey(m.Namespace, m.Name)] = u
+	}
+	return usage, nil
+}
+
+func formatPrice(p float64) string {
+	return "$" + strconv.FormatFloat(p, 'f', 4, 64)
+}
+
+/*
+Print cost estimates,
+rolled up per deployment, namespace or team label
+*/
+func PrintCost(namespace, priceFile, by, groupBy, teamLabel string) error {
+	prices, err := LoadPriceTable(priceFile)
+	if err != nil {
+		return err
+	}
+	nodes, err := node.ListNodes()
+	if err != nil {
+		return err
+	}
+	pods, err := pod.GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+
+	replicaSets, err := deployment.GetReplicaSets(&namespace)
+	if err != nil {
+		return err
+	}
+
+	var usage map[string]Usage
+	if by == "usage" {
+		usage, err = getUsage(namespace)
+		if err != nil {
+			return err
+		}
+	}
+	costs, missing := Allocate(nodes, pods.Items, deployment.GetPodDeployments(pods.Items, replicaSets.Items), usage, prices, teamLabel)
+
+	var clusterHourly, allocatedHourly float64
+	for _, n := range nodes {
+		price, _ := prices.NodePrice(n)
+		clusterHourly += price
+	}
+
+	type group struct {
+		namespace, name string
+		pods            int
+		hourly          float64
+	}
+	groups := make(map[string]*group)
+	for _, c := range costs {
+		allocatedHourly += c.Hourly
+		var key, ns, name string
+		switch groupBy {
+		case "deployment":
+			name = c.Deployment
+			if name == "" {
+				name = "<none>"
+			}
+			ns = c.Namespace
+			key = ns + "/" + name
+		case "team":
+			name = c.Team
+			if name == "" {
+				name = "<none>"
+			}
+			key = name
+		default:
+			name = c.Namespace
+			key = name
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{namespace: ns, name: name}
+			groups[key] = g
+		}
+		g.pods++
+		g.hourly += c.Hourly
+	}
+
+	var sorted []*group
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].hourly > sorted[j].hourly
+	})
+
+	// Print Header
+	fmt.Println(lineBreaker)
+	fmt.Println("Cluster Cost: \t\tHourly: " + formatPrice(clusterHourly) + "\t\tMonthly: " + formatPrice(clusterHourly*hoursPerMonth))
+	fmt.Println("Allocated (" + by + "): \tHourly: " + formatPrice(allocatedHourly) + "\t\tMonthly: " + formatPrice(allocatedHourly*hoursPerMonth))
+	fmt.Println(lineBreaker)
+
+	w := style.NewWriter()
+	switch groupBy {
+	case "deployment":
+		fmt.Fprintln(w, "DEPLOYMENT\t\tNAMESPACE\t\tPODS\t\tHOURLY\t\tMONTHLY")
+	default:
+		fmt.Fprintln(w, strings.ToUpper(groupBy)+"\t\tPODS\t\tHOURLY\t\tMONTHLY")
+	}
+	for _, g := range sorted {
+		cols := []string{g.name}
+		if groupBy == "deployment" {
+			cols = append(cols, g.namespace)
+		}
+		cols = append(cols, strconv.Itoa(g.pods), formatPrice(g.hourly), formatPrice(g.hourly*hoursPerMonth))
+		fmt.Fprintln(w, strings.Join(cols, "\t\t"))
+	}
+	w.Flush()
+
+	if len(missing) != 0 {
+		fmt.Fprintln(os.Stderr, "\nno price found for "+strconv.Itoa(len(missing))+" nodes, they are counted as free: "+strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name, instanceType, capacityType, cpu, mem string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			"node.kubernetes.io/instance-type": instanceType,
+			"eks.amazonaws.com/capacityType":   capacityType,
+		}},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(mem),
+		}},
+	}
+}
+
+func testPod(name, nodeName, cpu, mem string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "apps",
+			Labels:          map[string]string{"team": "payments"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet"}},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(mem),
+			}}}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	if err := os.WriteFile(path, []byte("m5.xlarge:\n  ON_DEMAND: 0.192\n  SPOT: 0.0672\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := LoadPriceTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := prices["m5.xlarge"]["SPOT"]; got != 0.0672 {
+		t.Errorf("SPOT price = %v, want 0.0672", got)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	prices := PriceTable{"m5.xlarge": {"ON_DEMAND": 0.2, "SPOT": 0.08}}
+	nodes := []v1.Node{
+		testNode("od-1", "m5.xlarge", "ON_DEMAND", "4", "16Gi"),
+		testNode("spot-1", "m5.xlarge", "SPOT", "4", "16Gi"),
+		testNode("unknown-1", "c7g.large", "SPOT", "2", "4Gi"),
+	}
+	pods := []v1.Pod{
+		testPod("web-6d4cf56db6-abcde", "od-1", "2", "8Gi"),
+		testPod("web-6d4cf56db6-fghij", "spot-1", "1", "4Gi"),
+		testPod("pending-6d4cf56db6-klmno", "", "1", "1Gi"),
+	}
+
+	// the controller is not the first owner of the first pod, the ReplicaSet of the second has no deployment
+	controller := true
+	pods[0].OwnerReferences = []metav1.OwnerReference{{Kind: "ConfigMap", UID: "cm"}, {Kind: "ReplicaSet", UID: "rs-web", Controller: &controller}}
+	pods[1].OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", UID: "rs-bare", Controller: &controller}}
+	replicaSets := []appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web-6d4cf56db6", UID: "rs-web", OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web-6d4cf56db6", UID: "rs-bare"}},
+	}
+	deployments := deployment.GetPodDeployments(pods, replicaSets)
+	costs, missing := Allocate(nodes, pods, deployments, nil, prices, "team")
+	if len(missing) != 1 || missing[0] != "unknown-1" {
+		t.Errorf("missing = %v, want [unknown-1]", missing)
+	}
+	if len(costs) != 2 {
+		t.Fatalf("got %d pod costs, want 2", len(costs))
+	}
+	// half of the on demand node and a quarter of the spot node
+	if math.Abs(costs[0].Hourly-0.1) > 1e-9 || math.Abs(costs[1].Hourly-0.02) > 1e-9 {
+		t.Errorf("hourly = %v, %v, want 0.1, 0.02", costs[0].Hourly, costs[1].Hourly)
+	}
+	if costs[0].Deployment != "web" || costs[0].Team != "payments" {
+		t.Errorf("got deployment %q team %q, want web payments", costs[0].Deployment, costs[0].Team)
+	}
+	if costs[1].Deployment != "" {
+		t.Errorf("got deployment %q for a pod of a bare ReplicaSet, want none", costs[1].Deployment)
+	}
+
+	usage := map[string]Usage{"apps/web-6d4cf56db6-abcde": {CPU: 0.4, Mem: 1.6 * 1024 * 1024 * 1024}}
+	costs, _ = Allocate(nodes, pods, deployments, usage, prices, "team")
+	if math.Abs(costs[0].Hourly-0.02) > 1e-9 || costs[1].Hourly != 0 {
+		t.Errorf("hourly by usage = %v, %v, want 0.02, 0", costs[0].Hourly, costs[1].Hourly)
+	}
+}
@@ -17,52 +17,30 @@ limitations under the License.
 package deployment
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"regexp"
-	"strconv"
 	"strings"
-	"text/tabwriter"
 
-	k8sclient "github.com/sam0392in/kshow/internal/client"
-	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/cache"
 	"github.com/sam0392in/kshow/internal/pod"
-	"go.uber.org/zap"
+	"github.com/sam0392in/kshow/internal/style"
 	v1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	appsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 )
 
-var (
-	logger *zap.Logger
-)
-
-func init() {
-	logger, _ = zap.NewProduction()
-
-}
-
-func client(namespace *string) appsv1.DeploymentInterface {
-	clientset, err := k8sclient.GetK8sClient()
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	deployClient := clientset.AppsV1().Deployments(*namespace)
-	return deployClient
-}
-
 /*
 List Deployments,
 Returns list.items of Deployments
 */
 func GetDeployments(namespace *string) (*v1.DeploymentList, error) {
-	deploymentsClient := client(namespace)
-	list, err := deploymentsClient.List(context.TODO(), metav1.ListOptions{})
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.Deployments(*namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return nil, err
 	}
-	return list, err
+	return &v1.DeploymentList{Items: items}, nil
 }
 
 // Extract Deployment name from pod name
@@ -84,124 +62,47 @@ func GetDeploymentFromPod(podName string) string {
 	return deploymentName
 }
 
-// Print deployments
-func ListDeployments(namespace string) {
+// List deployments with QoS class and missing requests or limits of the pod template
+func ListDeploymentResources(namespace string) error {
 	deployList, err := GetDeployments(&namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return err
 	}
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "DEPLOYMENT\tNAMESPACE\tREPLICAS")
+	w := style.NewWriter()
+	fmt.Fprintln(w, "DEPLOYMENT\t\tNAMESPACE\t\tQOS\t\tMISSING-REQUESTS\t\tMISSING-LIMITS\t\tFLAG")
 	for _, d := range deployList.Items {
-		r := d.Spec.Replicas
-		replicas := *r
+		spec := d.Spec.Template.Spec
+		qos := pod.GetQOSClass(spec)
+
+		var missingRequests, missingLimits []string
+		for _, c := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+			r := pod.GetContainerResources(c.Resources)
+			if r.RequestCPU.IsZero() || r.RequestMem.IsZero() {
+				missingRequests = append(missingRequests, c.Name)
+			}
+			if r.LimitCPU.IsZero() || r.LimitMem.IsZero() {
+				missingLimits = append(missingLimits, c.Name)
+			}
+		}
 
-		tr := d.Spec.Template.Spec.Tolerations
-		var tolerations []string
-		for _, t := range tr {
-			tl := t.Key + "-" + string(t.Operator) + "-" + t.Value + "-" + string(t.Effect)
-			tolerations = append(tolerations, tl)
+		var flags []string
+		if qos == corev1.PodQOSBestEffort {
+			flags = append(flags, "BEST-EFFORT")
+		}
+		if len(missingLimits) != 0 {
+			flags = append(flags, "MISSING-LIMITS")
 		}
 
-		data := d.Name + "\t" + d.Namespace + "\t" + strconv.FormatInt(int64(replicas), 10)
+		data := d.Name + "\t\t" + d.Namespace + "\t\t" + string(qos) + "\t\t" + joinOrDash(missingRequests) + "\t\t" + joinOrDash(missingLimits) + "\t\t" + joinOrDash(flags)
 		fmt.Fprintln(w, data)
-
 	}
 	w.Flush()
+	return nil
 }
 
-// Get Pod extra details of Deployment
-func getPodDistribution(namespace, deployment string) (int, int, int, int) {
-	var podOnDemand, podSpot, podready, podtotal int
-	podOnDemand = 0
-	podSpot = 0
-	podready = 0
-	podtotal = 0
-	pods, err := pod.GetPods(&namespace)
-	if err != nil {
-		logger.Error(err.Error())
+func joinOrDash(s []string) string {
+	if len(s) == 0 {
+		return "-"
 	}
-	// get all nodes in the cluster
-	nodes, err := node.ListNodes()
-	if err != nil {
-		logger.Error(err.Error())
-	}
-
-	for _, pod := range pods.Items {
-		podDeploymentName := GetDeploymentFromPod(pod.Name)
-
-		re := regexp.MustCompile("^" + deployment + "$")
-		exactMatch := re.MatchString(podDeploymentName)
-
-		if exactMatch {
-			podtotal += 1
-			if pod.Status.Phase == "Running" {
-				podready += 1
-				for _, node := range nodes {
-					podNode := pod.Spec.NodeName
-					nodeName := node.ObjectMeta.Labels["kubernetes.io/hostname"]
-					nodeTenancy := node.ObjectMeta.Labels["eks.amazonaws.com/capacityType"]
-					if podNode == nodeName {
-						if nodeTenancy == "ON_DEMAND" {
-							podOnDemand += 1
-						} else if nodeTenancy == "SPOT" {
-							podSpot += 1
-						}
-						break
-					}
-				}
-			}
-		}
-
-	}
-
-	return podOnDemand, podSpot, podready, podtotal
-}
-
-// List deployments with Detailed
-func ListDeploymentDetailed(namespace string) {
-	deployList, err := GetDeployments(&namespace)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "DEPLOYMENT\tNAMESPACE\t\tREADY\tDISTRIBUTION\t\tTOLERATIONS")
-	for _, d := range deployList.Items {
-		r := d.Spec.Replicas
-		replicas := *r
-
-		tr := d.Spec.Template.Spec.Tolerations
-		var tolerations []string
-		for _, t := range tr {
-			tl := t.Key + "-" + string(t.Operator) + "-" + t.Value + "-" + string(t.Effect)
-			tolerations = append(tolerations, tl)
-		}
-
-		// get distribution
-		var distribution string
-		// var percentageOndemand, percentageSpot float64
-		podsOnDemand, podSpot, podReady, _ := getPodDistribution(namespace, d.Name)
-		/*
-			Disabled % distribution calculation.
-			Currently enabled is distribution based on count of pods
-		*/
-		// totalpods := podsOnDemand + podSpot
-		// if totalpods != 0 {
-		// 	percentageOndemand = math.Round(float64(podsOnDemand * 100 / totalpods))
-		// 	percentageSpot = math.Round(float64(podSpot * 100 / totalpods))
-		// } else {
-		// 	percentageOndemand = 0
-		// 	percentageSpot = 0
-		// }
-
-		podReadyStatus := strconv.Itoa(podReady) + "/" + strconv.Itoa(int(replicas))
-
-		// distribution = "OD:" + fmt.Sprintf("%.0f", percentageOndemand) + " SP:" + fmt.Sprintf("%.0f", percentageSpot)
-
-		distribution = "OD:" + strconv.Itoa(podsOnDemand) + " SP:" + strconv.Itoa(podSpot)
-		data := d.Name + "\t" + d.Namespace + "\t\t" + podReadyStatus + "\t" + distribution + "\t\t" + strings.Join(tolerations, "::")
-		fmt.Fprintln(w, data)
-
-	}
-	w.Flush()
+	return strings.Join(s, ",")
 }
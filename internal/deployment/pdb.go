@@ -0,0 +1,106 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sam0392in/kshow/internal/pdb"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Get the names of the PDBs covering a deployment's pods
+func getDeploymentPDBs(d v1.Deployment, pdbs []policyv1.PodDisruptionBudget) []string {
+	var names []string
+	for _, p := range pdb.FindForPods(pdbs, d.Namespace, d.Spec.Template.Labels) {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func intOrStringOrDash(v *intstr.IntOrString) string {
+	if v == nil {
+		return "-"
+	}
+	return v.String()
+}
+
+// Get the names of the deployments whose pods a PDB covers
+func getPDBDeployments(p policyv1.PodDisruptionBudget, deployments []v1.Deployment) []string {
+	var names []string
+	for _, d := range deployments {
+		if pdb.Matches(p, d.Namespace, d.Spec.Template.Labels) {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// Flag PDBs blocking every eviction and PDBs covering no deployment
+func getPDBFlags(p policyv1.PodDisruptionBudget, deployments []string) []string {
+	var flags []string
+	if p.Status.DisruptionsAllowed == 0 {
+		flags = append(flags, "ZERO-DISRUPTIONS")
+	}
+	if len(deployments) == 0 {
+		flags = append(flags, "NO-WORKLOAD")
+	}
+	return flags
+}
+
+// List PDBs with the deployments they cover, and deployments without a PDB
+func ListPodDisruptionBudgets(namespace string) error {
+	pdbList, err := pdb.GetPodDisruptionBudgets(&namespace)
+	if err != nil {
+		return err
+	}
+	deployList, err := GetDeployments(&namespace)
+	if err != nil {
+		return err
+	}
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "PDB\t\tNAMESPACE\t\tMIN-AVAILABLE\t\tMAX-UNAVAILABLE\t\tHEALTHY\t\tALLOWED-DISRUPTIONS\t\tDEPLOYMENTS\t\tFLAG")
+	for _, p := range pdbList.Items {
+		deployments := getPDBDeployments(p, deployList.Items)
+		flags := getPDBFlags(p, deployments)
+		healthy := strconv.Itoa(int(p.Status.CurrentHealthy)) + "/" + strconv.Itoa(int(p.Status.DesiredHealthy))
+
+		data := p.Name + "\t\t" + p.Namespace + "\t\t" + intOrStringOrDash(p.Spec.MinAvailable) + "\t\t" + intOrStringOrDash(p.Spec.MaxUnavailable) + "\t\t" + healthy + "\t\t" + strconv.Itoa(int(p.Status.DisruptionsAllowed)) + "\t\t" + joinOrDash(deployments) + "\t\t" + joinOrDash(flags)
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+
+	var uncovered []string
+	for _, d := range deployList.Items {
+		if len(getDeploymentPDBs(d, pdbList.Items)) == 0 {
+			uncovered = append(uncovered, d.Namespace+"/"+d.Name)
+		}
+	}
+	if len(uncovered) != 0 {
+		fmt.Println("\nDeployments without a PodDisruptionBudget:")
+		for _, d := range uncovered {
+			fmt.Println("  " + d)
+		}
+	}
+	return nil
+}
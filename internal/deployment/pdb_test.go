@@ -0,0 +1,82 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testDeployment(namespace, name string, labels map[string]string) v1.Deployment {
+	d := v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	d.Spec.Template.Labels = labels
+	return d
+}
+
+func testPDB(namespace string, selector *metav1.LabelSelector, allowed int32) policyv1.PodDisruptionBudget {
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: namespace},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+	}
+}
+
+func TestGetPDBDeployments(t *testing.T) {
+	deployments := []v1.Deployment{
+		testDeployment("app", "web", map[string]string{"app": "web"}),
+		testDeployment("app", "api", map[string]string{"app": "api"}),
+		testDeployment("db", "web", map[string]string{"app": "web"}),
+	}
+	tests := []struct {
+		name string
+		pdb  policyv1.PodDisruptionBudget
+		want []string
+	}{
+		{"match labels", testPDB("app", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, 1), []string{"web"}},
+		{"catch-all", testPDB("app", &metav1.LabelSelector{}, 1), []string{"web", "api"}},
+		{"nil selector", testPDB("app", nil, 1), nil},
+		{"no workload", testPDB("app", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "worker"}}, 1), nil},
+	}
+	for _, tt := range tests {
+		if got := getPDBDeployments(tt.pdb, deployments); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: getPDBDeployments() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetPDBFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     int32
+		deployments []string
+		want        []string
+	}{
+		{"healthy", 1, []string{"web"}, nil},
+		{"zero disruptions", 0, []string{"web"}, []string{"ZERO-DISRUPTIONS"}},
+		{"no workload", 1, nil, []string{"NO-WORKLOAD"}},
+		{"both", 0, nil, []string{"ZERO-DISRUPTIONS", "NO-WORKLOAD"}},
+	}
+	for _, tt := range tests {
+		if got := getPDBFlags(testPDB("app", &metav1.LabelSelector{}, tt.allowed), tt.deployments); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: getPDBFlags() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/sam0392in/kshow/internal/cache"
+	"github.com/sam0392in/kshow/internal/kerrors"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Annotation set by the deployment controller on each ReplicaSet
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+/*
+List ReplicaSets,
+Returns list.items of ReplicaSets
+*/
+func GetReplicaSets(namespace *string) (*v1.ReplicaSetList, error) {
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.ReplicaSets(*namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ReplicaSetList{Items: items}, nil
+}
+
+// Find a deployment by name, the namespace is optional when the name is unique
+func FindDeployment(namespace, name string) (v1.Deployment, error) {
+	deployList, err := GetDeployments(&namespace)
+	if err != nil {
+		return v1.Deployment{}, err
+	}
+	var found []v1.Deployment
+	for _, d := range deployList.Items {
+		if d.Name == name {
+			found = append(found, d)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return v1.Deployment{}, kerrors.NotFound("deployment", name)
+	case 1:
+		return found[0], nil
+	}
+	return v1.Deployment{}, errors.New("deployment " + name + " exists in several namespaces, specify one with -n")
+}
+
+// Get the revision of a ReplicaSet, 0 if it has none
+func GetRevision(rs v1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// Get the ReplicaSets controlled by a deployment, newest revision first
+func GetDeploymentReplicaSets(d v1.Deployment, replicaSets []v1.ReplicaSet) []v1.ReplicaSet {
+	var owned []v1.ReplicaSet
+	for _, rs := range replicaSets {
+		if ref := metav1.GetControllerOf(&rs); ref != nil && ref.UID == d.UID {
+			owned = append(owned, rs)
+		}
+	}
+	sort.SliceStable(owned, func(i, j int) bool {
+		return GetRevision(owned[i]) > GetRevision(owned[j])
+	})
+	return owned
+}
+
+/*
+Get the deployment of each pod keyed by namespace/pod,
+following the ownerReferences from the pod to its ReplicaSet and from there to the deployment.
+Pods of other workloads, e.g. StatefulSets and DaemonSets, are left out
+*/
+func GetPodDeployments(pods []corev1.Pod, replicaSets []v1.ReplicaSet) map[string]string {
+	owners := make(map[types.UID]string, len(replicaSets))
+	for i := range replicaSets {
+		if ref := metav1.GetControllerOf(&replicaSets[i]); ref != nil && ref.Kind == "Deployment" {
+			owners[replicaSets[i].UID] = ref.Name
+		}
+	}
+	deployments := make(map[string]string)
+	for i := range pods {
+		ref := metav1.GetControllerOf(&pods[i])
+		if ref == nil || ref.Kind != "ReplicaSet" {
+			continue
+		}
+		if name, ok := owners[ref.UID]; ok {
+			deployments[pods[i].Namespace+"/"+pods[i].Name] = name
+		}
+	}
+	return deployments
+}
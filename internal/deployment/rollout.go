@@ -0,0 +1,206 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+Get the rollout state of a deployment,
+follows the checks of kubectl rollout status
+*/
+func GetRolloutStatus(d v1.Deployment) string {
+	if d.Generation > d.Status.ObservedGeneration {
+		return "waiting for the deployment spec update to be observed"
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == v1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return "failed, progress deadline exceeded"
+		}
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	switch {
+	case d.Status.UpdatedReplicas < replicas:
+		return strconv.Itoa(int(d.Status.UpdatedReplicas)) + " of " + strconv.Itoa(int(replicas)) + " new replicas have been updated"
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return strconv.Itoa(int(d.Status.Replicas-d.Status.UpdatedReplicas)) + " old replicas are pending termination"
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		return strconv.Itoa(int(d.Status.AvailableReplicas)) + " of " + strconv.Itoa(int(d.Status.UpdatedReplicas)) + " updated replicas are available"
+	}
+	return "complete"
+}
+
+func containerImages(rs v1.ReplicaSet) map[string]string {
+	images := make(map[string]string)
+	for _, c := range rs.Spec.Template.Spec.InitContainers {
+		images[c.Name] = c.Image
+	}
+	for _, c := range rs.Spec.Template.Spec.Containers {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+/*
+Get the image changes between two revisions,
+e.g. app: nginx:1.24 -> nginx:1.25. Containers added or removed are listed with + and -
+*/
+func GetImageChanges(prev, next v1.ReplicaSet) []string {
+	prevImages, nextImages := containerImages(prev), containerImages(next)
+	var changes []string
+	for _, c := range append(append([]corev1.Container{}, next.Spec.Template.Spec.InitContainers...), next.Spec.Template.Spec.Containers...) {
+		image, ok := prevImages[c.Name]
+		switch {
+		case !ok:
+			changes = append(changes, "+"+c.Name+": "+c.Image)
+		case image != c.Image:
+			changes = append(changes, c.Name+": "+image+" -> "+c.Image)
+		}
+	}
+	for _, c := range append(append([]corev1.Container{}, prev.Spec.Template.Spec.InitContainers...), prev.Spec.Template.Spec.Containers...) {
+		if _, ok := nextImages[c.Name]; !ok {
+			changes = append(changes, "-"+c.Name+": "+c.Image)
+		}
+	}
+	return changes
+}
+
+// Get running pods of a ReplicaSet on on-demand and spot nodes, and its total pods
+func getReplicaSetDistribution(rs v1.ReplicaSet, pods []corev1.Pod, tenancy map[string]string) (int, int, int) {
+	var podOnDemand, podSpot, podTotal int
+	for _, p := range pods {
+		if ref := metav1.GetControllerOf(&p); ref == nil || ref.UID != rs.UID {
+			continue
+		}
+		podTotal++
+		if p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		switch tenancy[p.Spec.NodeName] {
+		case node.OnDemand:
+			podOnDemand++
+		case node.Spot:
+			podSpot++
+		}
+	}
+	return podOnDemand, podSpot, podTotal
+}
+
+// Print rollout progress, ReplicaSets and revision history of a deployment, target is deploy/<name> or <name>
+func PrintRollout(namespace, target string) error {
+	name := target
+	if i := strings.Index(target, "/"); i >= 0 {
+		switch target[:i] {
+		case "deploy", "deployment", "deployments":
+			name = target[i+1:]
+		default:
+			return errors.New("only deployments have a rollout: " + target)
+		}
+	}
+	d, err := FindDeployment(namespace, name)
+	if err != nil {
+		return err
+	}
+	rsList, err := GetReplicaSets(&d.Namespace)
+	if err != nil {
+		return err
+	}
+	pods, err := pod.GetPods(&d.Namespace)
+	if err != nil {
+		return err
+	}
+	nodes, err := node.ListNodes()
+	if err != nil {
+		return err
+	}
+	replicaSets := GetDeploymentReplicaSets(d, rsList.Items)
+	tenancy := node.GetNodeTenancy(nodes)
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	fmt.Println("Deployment " + d.Namespace + "/" + d.Name + ": " + GetRolloutStatus(d))
+	fmt.Println("Replicas: " + strconv.Itoa(int(replicas)) + " desired | " + strconv.Itoa(int(d.Status.UpdatedReplicas)) + " updated | " + strconv.Itoa(int(d.Status.ReadyReplicas)) + " ready | " + strconv.Itoa(int(d.Status.AvailableReplicas)) + " available")
+
+	fmt.Println()
+	w := style.NewWriter()
+	fmt.Fprintln(w, "CONDITION\t\tSTATUS\t\tREASON\t\tMESSAGE")
+	for _, c := range d.Status.Conditions {
+		if c.Type != v1.DeploymentProgressing && c.Type != v1.DeploymentAvailable {
+			continue
+		}
+		fmt.Fprintln(w, string(c.Type)+"\t\t"+string(c.Status)+"\t\t"+c.Reason+"\t\t"+c.Message)
+	}
+	w.Flush()
+
+	// the newest revision is the new ReplicaSet, older ones with pods are still scaling down
+	fmt.Println()
+	w = style.NewWriter()
+	fmt.Fprintln(w, "REPLICASET\t\tREVISION\t\tROLE\t\tDESIRED\t\tREADY\t\tPODS\t\tDISTRIBUTION")
+	for i, rs := range replicaSets {
+		desired := int32(0)
+		if rs.Spec.Replicas != nil {
+			desired = *rs.Spec.Replicas
+		}
+		if i != 0 && desired == 0 && rs.Status.Replicas == 0 {
+			continue
+		}
+		role := "OLD"
+		if i == 0 {
+			role = "NEW"
+		}
+		podsOnDemand, podSpot, podTotal := getReplicaSetDistribution(rs, pods.Items, tenancy)
+		data := rs.Name + "\t\t" + strconv.FormatInt(GetRevision(rs), 10) + "\t\t" + role + "\t\t" + strconv.Itoa(int(desired)) + "\t\t" + strconv.Itoa(int(rs.Status.ReadyReplicas)) + "\t\t" + strconv.Itoa(podTotal) + "\t\t" + "OD:" + strconv.Itoa(podsOnDemand) + " SP:" + strconv.Itoa(podSpot)
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = style.NewWriter()
+	fmt.Fprintln(w, "REVISION\t\tREPLICASET\t\tCREATED\t\tIMAGE-CHANGES")
+	for i, rs := range replicaSets {
+		var changes []string
+		if i+1 < len(replicaSets) {
+			changes = GetImageChanges(replicaSets[i+1], rs)
+		} else {
+			// oldest revision kept, list its images
+			for _, c := range rs.Spec.Template.Spec.Containers {
+				changes = append(changes, c.Name+": "+c.Image)
+			}
+		}
+		data := strconv.FormatInt(GetRevision(rs), 10) + "\t\t" + rs.Name + "\t\t" + rs.CreationTimestamp.Format("2006-01-02 15:04:05") + "\t\t" + joinOrDash(changes)
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
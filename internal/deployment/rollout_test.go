@@ -0,0 +1,155 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func testReplicaSet(name, revision string, owner types.UID, images ...string) v1.ReplicaSet {
+	controller := true
+	rs := v1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		UID:             types.UID(name),
+		Annotations:     map[string]string{revisionAnnotation: revision},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", UID: owner, Controller: &controller}},
+	}}
+	for i := 0; i+1 < len(images); i += 2 {
+		rs.Spec.Template.Spec.Containers = append(rs.Spec.Template.Spec.Containers, corev1.Container{Name: images[i], Image: images[i+1]})
+	}
+	return rs
+}
+
+func TestGetDeploymentReplicaSets(t *testing.T) {
+	d := v1.Deployment{ObjectMeta: metav1.ObjectMeta{UID: "web"}}
+	replicaSets := []v1.ReplicaSet{
+		testReplicaSet("web-1", "1", "web"),
+		testReplicaSet("api-1", "1", "api"),
+		testReplicaSet("web-3", "3", "web"),
+		testReplicaSet("web-2", "2", "web"),
+	}
+	var got []string
+	for _, rs := range GetDeploymentReplicaSets(d, replicaSets) {
+		got = append(got, rs.Name)
+	}
+	want := []string{"web-3", "web-2", "web-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDeploymentReplicaSets() = %v, want %v", got, want)
+	}
+}
+
+func TestGetImageChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		prev, next v1.ReplicaSet
+		want       []string
+	}{
+		{
+			name: "unchanged",
+			prev: testReplicaSet("web-1", "1", "web", "app", "nginx:1.24"),
+			next: testReplicaSet("web-2", "2", "web", "app", "nginx:1.24"),
+		},
+		{
+			name: "image bump",
+			prev: testReplicaSet("web-1", "1", "web", "app", "nginx:1.24", "proxy", "envoy:1.27"),
+			next: testReplicaSet("web-2", "2", "web", "app", "nginx:1.25", "proxy", "envoy:1.27"),
+			want: []string{"app: nginx:1.24 -> nginx:1.25"},
+		},
+		{
+			name: "container added and removed",
+			prev: testReplicaSet("web-1", "1", "web", "app", "nginx:1.24", "proxy", "envoy:1.27"),
+			next: testReplicaSet("web-2", "2", "web", "app", "nginx:1.24", "log", "fluent-bit:2.1"),
+			want: []string{"+log: fluent-bit:2.1", "-proxy: envoy:1.27"},
+		},
+	}
+	for _, tt := range tests {
+		if got := GetImageChanges(tt.prev, tt.next); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: GetImageChanges() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetImageChangesKeepsInitContainers(t *testing.T) {
+	prev := testReplicaSet("web-1", "1", "web", "app", "nginx:1.24")
+	next := testReplicaSet("web-2", "2", "web", "app", "nginx:1.25")
+	// spare capacity that appending the containers to the init containers would write into
+	init := make([]corev1.Container, 1, 4)
+	init[0] = corev1.Container{Name: "migrate", Image: "migrate:1"}
+	next.Spec.Template.Spec.InitContainers = init
+	spare := init[:2]
+
+	GetImageChanges(prev, next)
+	if spare[1].Name != "" {
+		t.Errorf("GetImageChanges() wrote %q into the backing array of the init containers", spare[1].Name)
+	}
+}
+
+func TestGetRolloutStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status v1.DeploymentStatus
+		want   string
+	}{
+		{"complete", v1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, "complete"},
+		{"updating", v1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 1, AvailableReplicas: 3}, "1 of 3 new replicas have been updated"},
+		{"old pending termination", v1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3}, "1 old replicas are pending termination"},
+		{"not available", v1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2}, "2 of 3 updated replicas are available"},
+		{"deadline exceeded", v1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 1, Conditions: []v1.DeploymentCondition{
+			{Type: v1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}}, "failed, progress deadline exceeded"},
+	}
+	for _, tt := range tests {
+		d := v1.Deployment{Spec: v1.DeploymentSpec{Replicas: int32Ptr(3)}, Status: tt.status}
+		if got := GetRolloutStatus(d); got != tt.want {
+			t.Errorf("%s: GetRolloutStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetReplicaSetDistribution(t *testing.T) {
+	rs := testReplicaSet("web-2", "2", "web")
+	tenancy := map[string]string{"od-1": "ON_DEMAND", "spot-1": "SPOT"}
+	controller := true
+	testPod := func(owner types.UID, nodeName string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", UID: owner, Controller: &controller}}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []corev1.Pod{
+		testPod("web-2", "od-1", corev1.PodRunning),
+		testPod("web-2", "spot-1", corev1.PodRunning),
+		testPod("web-2", "spot-1", corev1.PodRunning),
+		testPod("web-2", "", corev1.PodPending),
+		testPod("web-1", "od-1", corev1.PodRunning),
+	}
+	onDemand, spot, total := getReplicaSetDistribution(rs, pods, tenancy)
+	if onDemand != 1 || spot != 2 || total != 4 {
+		t.Errorf("getReplicaSetDistribution() = %d, %d, %d, want 1, 2, 4", onDemand, spot, total)
+	}
+}
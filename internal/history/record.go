@@ -0,0 +1,68 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Sampler takes a sample of the cluster
+type Sampler func(ctx context.Context) (Sample, error)
+
+/*
+Record a sample every interval into the file until duration has passed or ctx
+is done. The first sample must succeed, later failures to sample are printed
+on stderr and the recording goes on
+*/
+func Record(ctx context.Context, path string, interval, duration time.Duration, sample Sampler) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", interval)
+	}
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	s, err := sample(ctx)
+	if err != nil {
+		return err
+	}
+	if err := Append(path, s); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		s, err := sample(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, "kshow: sample skipped: "+err.Error())
+			}
+			continue
+		}
+		if err := Append(path, s); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/style"
+)
+
+// Stats of a series of values
+type Stats struct {
+	Min, Avg, P95, Max int64
+}
+
+// Summarize values, p95 is the nearest rank
+func Summarize(values []int64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return Stats{
+		Min: sorted[0],
+		Avg: sum / int64(len(sorted)),
+		P95: sorted[rank],
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// Row is the cpu and memory stats of a container, deployment or node, Key is its name columns
+type Row struct {
+	Key         []string
+	Samples     int
+	CPU, Memory Stats
+}
+
+// Usage series by key, in the order keys were first seen
+type series struct {
+	keys     [][]string
+	cpu, mem map[string][]int64
+}
+
+func newSeries() *series {
+	return &series{cpu: make(map[string][]int64), mem: make(map[string][]int64)}
+}
+
+func (s *series) add(key []string, cpu, mem int64) {
+	k := strings.Join(key, "/")
+	if _, ok := s.cpu[k]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.cpu[k] = append(s.cpu[k], cpu)
+	s.mem[k] = append(s.mem[k], mem)
+}
+
+func (s *series) rows() []Row {
+	rows := make([]Row, 0, len(s.keys))
+	for _, key := range s.keys {
+		k := strings.Join(key, "/")
+		rows = append(rows, Row{Key: key, Samples: len(s.cpu[k]), CPU: Summarize(s.cpu[k]), Memory: Summarize(s.mem[k])})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return strings.Join(rows[i].Key, "/") < strings.Join(rows[j].Key, "/") })
+	return rows
+}
+
+// Stats per namespace/pod/container
+func ContainerStats(samples []Sample) []Row {
+	s := newSeries()
+	for _, sample := range samples {
+		for _, c := range sample.Containers {
+			s.add([]string{c.Namespace, c.Pod, c.Container}, c.CPU, c.Memory)
+		}
+	}
+	return s.rows()
+}
+
+/*
+Stats per namespace/deployment of the usage summed over its pods at each sample,
+so a rollout replacing pods keeps one series. Pods of other workloads are left out
+*/
+func DeploymentStats(samples []Sample) []Row {
+	s := newSeries()
+	for _, sample := range samples {
+		type total struct{ cpu, mem int64 }
+		var keys []string
+		totals := make(map[string]*total)
+		for _, c := range sample.Containers {
+			if c.Deployment == "" {
+				continue
+			}
+			k := c.Namespace + "/" + c.Deployment
+			if totals[k] == nil {
+				totals[k] = &total{}
+				keys = append(keys, k)
+			}
+			totals[k].cpu += c.CPU
+			totals[k].mem += c.Memory
+		}
+		for _, k := range keys {
+			namespace, name, _ := strings.Cut(k, "/")
+			s.add([]string{namespace, name}, totals[k].cpu, totals[k].mem)
+		}
+	}
+	return s.rows()
+}
+
+// Stats per node
+func NodeStats(samples []Sample) []Row {
+	s := newSeries()
+	for _, sample := range samples {
+		for _, n := range sample.Nodes {
+			s.add([]string{n.Name}, n.CPU, n.Memory)
+		}
+	}
+	return s.rows()
+}
+
+func formatCPU(v int64) string {
+	return strconv.FormatInt(v, 10) + "m"
+}
+
+func formatMem(v int64) string {
+	return strconv.FormatInt(v/1048576, 10) + "Mi"
+}
+
+/*
+Print min, avg, p95 and max of the recorded usage in the window before
+the last sample, per container of pods, per deployment or per node
+*/
+func PrintStats(path string, window time.Duration, object, namespace string) error {
+	samples, err := Read(path, window)
+	if err != nil {
+		return err
+	}
+	if namespace != "" {
+		samples = inNamespace(samples, namespace)
+	}
+
+	var (
+		header string
+		rows   []Row
+	)
+	switch object {
+	case "", "pods", "pod", "po":
+		header, rows = "NAMESPACE\t\tPOD\t\tCONTAINER", ContainerStats(samples)
+	case "deployment", "deployments", "deploy":
+		header, rows = "NAMESPACE\t\tDEPLOYMENT", DeploymentStats(samples)
+	case "node", "nodes", "no":
+		header, rows = "NODE", NodeStats(samples)
+	default:
+		return errors.New("recorded stats are kept for pods, deployments and nodes, not " + object)
+	}
+
+	first, last := samples[0].Time, samples[len(samples)-1].Time
+	fmt.Printf("%d samples from %s to %s (%s)\n\n", len(samples), first.Local().Format(time.RFC3339), last.Local().Format(time.RFC3339), last.Sub(first).Round(time.Second))
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, header+"\t\tSAMPLES\t\tCPU-MIN\t\tCPU-AVG\t\tCPU-P95\t\tCPU-MAX\t\tMEM-MIN\t\tMEM-AVG\t\tMEM-P95\t\tMEM-MAX")
+	for _, r := range rows {
+		data := strings.Join(r.Key, "\t\t") + "\t\t" + strconv.Itoa(r.Samples) +
+			"\t\t" + formatCPU(r.CPU.Min) + "\t\t" + formatCPU(r.CPU.Avg) + "\t\t" + formatCPU(r.CPU.P95) + "\t\t" + formatCPU(r.CPU.Max) +
+			"\t\t" + formatMem(r.Memory.Min) + "\t\t" + formatMem(r.Memory.Avg) + "\t\t" + formatMem(r.Memory.P95) + "\t\t" + formatMem(r.Memory.Max)
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
+
+// Keep the containers of a namespace, nodes are kept
+func inNamespace(samples []Sample, namespace string) []Sample {
+	out := make([]Sample, len(samples))
+	for i, s := range samples {
+		out[i] = Sample{Time: s.Time, Nodes: s.Nodes}
+		for _, c := range s.Containers {
+			if c.Namespace == namespace {
+				out[i].Containers = append(out[i].Containers, c)
+			}
+		}
+	}
+	return out
+}
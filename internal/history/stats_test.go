@@ -0,0 +1,125 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestSummarize(t *testing.T) {
+	values := make([]int64, 0, 20)
+	for i := int64(20); i >= 1; i-- {
+		values = append(values, i*10)
+	}
+	tests := []struct {
+		name   string
+		values []int64
+		want   Stats
+	}{
+		{"empty", nil, Stats{}},
+		{"one", []int64{7}, Stats{7, 7, 7, 7}},
+		{"twenty", values, Stats{Min: 10, Avg: 105, P95: 190, Max: 200}},
+	}
+	for _, tt := range tests {
+		if got := Summarize(tt.values); got != tt.want {
+			t.Errorf("%s: Summarize() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if values[0] != 200 {
+		t.Errorf("Summarize() sorted its input")
+	}
+}
+
+func TestDeploymentStats(t *testing.T) {
+	container := func(pod string, cpu int64) ContainerSample {
+		return ContainerSample{Namespace: "app", Pod: pod, Container: "web", Deployment: "web", CPU: cpu, Memory: cpu << 20}
+	}
+	samples := []Sample{
+		{Time: start, Containers: []ContainerSample{container("web-1-a", 100), container("web-1-b", 200)}},
+		// a rollout replaced the pods
+		{Time: start.Add(time.Minute), Containers: []ContainerSample{container("web-2-c", 500)}},
+	}
+
+	got := DeploymentStats(samples)
+	want := []Row{{
+		Key:     []string{"app", "web"},
+		Samples: 2,
+		CPU:     Stats{Min: 300, Avg: 400, P95: 500, Max: 500},
+		Memory:  Stats{Min: 300 << 20, Avg: 400 << 20, P95: 500 << 20, Max: 500 << 20},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeploymentStats() = %+v, want %+v", got, want)
+	}
+
+	if rows := ContainerStats(samples); len(rows) != 3 || rows[0].Key[1] != "web-1-a" || rows[2].Samples != 1 {
+		t.Errorf("ContainerStats() = %+v, want one row per pod container", rows)
+	}
+}
+
+func TestDeploymentStatsOwners(t *testing.T) {
+	controller := true
+	owned := func(name, kind, owner string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "app", Name: name, UID: types.UID(name), OwnerReferences: []metav1.OwnerReference{
+			{Kind: kind, Name: owner, UID: types.UID(owner), Controller: &controller},
+		}}
+	}
+	pods := []v1.Pod{
+		{ObjectMeta: owned("web-5d8f7c9b6-x2x9p", "ReplicaSet", "web-5d8f7c9b6")},
+		// a pod name the template hash heuristic splits wrong
+		{ObjectMeta: owned("api-v2-7f9c-abcde", "ReplicaSet", "api-v2-7f9c")},
+		{ObjectMeta: owned("db-0", "StatefulSet", "db")},
+		{ObjectMeta: owned("my-ds-abcde", "DaemonSet", "my-ds")},
+	}
+	replicaSets := []appsv1.ReplicaSet{
+		{ObjectMeta: owned("web-5d8f7c9b6", "Deployment", "web")},
+		{ObjectMeta: owned("api-v2-7f9c", "Deployment", "api-v2")},
+	}
+	metricsList := &v1beta1.PodMetricsList{}
+	for _, p := range pods {
+		metricsList.Items = append(metricsList.Items, v1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: p.Name},
+			Containers: []v1beta1.ContainerMetrics{{
+				Name:  "main",
+				Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("1Mi")},
+			}},
+		})
+	}
+
+	s := NewSample(start, metricsList, nil, deployment.GetPodDeployments(pods, replicaSets))
+	var got [][]string
+	for _, row := range DeploymentStats([]Sample{s}) {
+		got = append(got, row.Key)
+	}
+	want := [][]string{{"app", "api-v2"}, {"app", "web"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DeploymentStats() keys = %v, want %v without the StatefulSet and DaemonSet pods", got, want)
+	}
+	if rows := ContainerStats([]Sample{s}); len(rows) != 4 {
+		t.Errorf("ContainerStats() = %d rows, want every pod", len(rows))
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package history records metrics-server samples into a local file and
+summarizes them over a window. The file holds one JSON sample per line and
+is only appended to, so a recording stopped halfway stays readable
+*/
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Sample is the usage of every container and node at one instant
+type Sample struct {
+	Time       time.Time         `json:"time"`
+	Containers []ContainerSample `json:"containers,omitempty"`
+	Nodes      []NodeSample      `json:"nodes,omitempty"`
+}
+
+// ContainerSample is the usage of a container, cpu in millicores and memory in bytes
+type ContainerSample struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	// Empty for pods not owned by a deployment
+	Deployment string `json:"deployment,omitempty"`
+	CPU        int64  `json:"cpu"`
+	Memory     int64  `json:"mem"`
+}
+
+// NodeSample is the usage of a node, cpu in millicores and memory in bytes
+type NodeSample struct {
+	Name   string `json:"name"`
+	CPU    int64  `json:"cpu"`
+	Memory int64  `json:"mem"`
+}
+
+/*
+Build a sample from pod and node metrics, either list may be nil.
+deployments maps namespace/pod to the deployment owning the pod, see deployment.GetPodDeployments
+*/
+func NewSample(at time.Time, pods *v1beta1.PodMetricsList, nodes *v1beta1.NodeMetricsList, deployments map[string]string) Sample {
+	s := Sample{Time: at.UTC()}
+	if pods != nil {
+		for _, m := range pods.Items {
+			for _, c := range m.Containers {
+				s.Containers = append(s.Containers, ContainerSample{
+					Namespace:  m.Namespace,
+					Pod:        m.Name,
+					Container:  c.Name,
+					Deployment: deployments[m.Namespace+"/"+m.Name],
+					CPU:        c.Usage.Cpu().MilliValue(),
+					Memory:     c.Usage.Memory().Value(),
+				})
+			}
+		}
+	}
+	if nodes != nil {
+		for _, m := range nodes.Items {
+			s.Nodes = append(s.Nodes, NodeSample{
+				Name:   m.Name,
+				CPU:    m.Usage.Cpu().MilliValue(),
+				Memory: m.Usage.Memory().Value(),
+			})
+		}
+	}
+	return s
+}
+
+// Append a sample to the file, creating it
+func Append(path string, s Sample) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+/*
+Read the samples of the file taken in the window before the last sample,
+all samples with a zero window. A line cut off by a stopped recording is skipped
+*/
+func Read(path string, window time.Duration) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		samples []Sample
+		bad     error
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		if bad != nil {
+			return nil, bad
+		}
+		var s Sample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			bad = fmt.Errorf("%s:%d: %w", path, line, err)
+			continue
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, errors.New(path + " has no samples")
+	}
+	if window <= 0 {
+		return samples, nil
+	}
+	from := samples[len(samples)-1].Time.Add(-window)
+	i := 0
+	for i < len(samples) && samples[i].Time.Before(from) {
+		i++
+	}
+	return samples[i:], nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNewSample(t *testing.T) {
+	pods := &v1beta1.PodMetricsList{Items: []v1beta1.PodMetrics{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f7c9b6-x2x9p", Namespace: "app"},
+		Containers: []v1beta1.ContainerMetrics{{
+			Name:  "web",
+			Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m"), v1.ResourceMemory: resource.MustParse("64Mi")},
+		}},
+	}}}
+	s := NewSample(start, pods, nil, map[string]string{"app/web-5d8f7c9b6-x2x9p": "web"})
+	want := ContainerSample{Namespace: "app", Pod: "web-5d8f7c9b6-x2x9p", Container: "web", Deployment: "web", CPU: 250, Memory: 64 << 20}
+	if len(s.Containers) != 1 || s.Containers[0] != want || len(s.Nodes) != 0 {
+		t.Errorf("NewSample() = %+v, want %+v", s, want)
+	}
+}
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	for i := 0; i < 4; i++ {
+		s := Sample{Time: start.Add(time.Duration(i) * time.Hour), Nodes: []NodeSample{{Name: "node-a", CPU: int64(i)}}}
+		if err := Append(path, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Read(path, 0)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Read() = %d samples, %v, want 4", len(all), err)
+	}
+	window, err := Read(path, 2*time.Hour)
+	if err != nil || len(window) != 3 || !window[0].Time.Equal(start.Add(time.Hour)) {
+		t.Errorf("Read(2h) = %+v, %v, want the last 3 samples", window, err)
+	}
+
+	// a recording stopped while writing leaves a cut off last line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T04:00:00Z","nod`)
+	f.Close()
+	if all, err := Read(path, 0); err != nil || len(all) != 4 {
+		t.Errorf("Read() with a cut off line = %d samples, %v, want 4", len(all), err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json\n{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path, 0); err == nil {
+		t.Errorf("Read() accepted a broken line before the last")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	calls := 0
+	err := Record(context.Background(), path, 10*time.Millisecond, 55*time.Millisecond, func(ctx context.Context) (Sample, error) {
+		calls++
+		if calls == 2 {
+			return Sample{}, errors.New("metrics-server restarting")
+		}
+		return Sample{Time: time.Now()}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := Read(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != calls-1 || len(samples) < 2 {
+		t.Errorf("Record() wrote %d samples of %d calls, want all but the failed one", len(samples), calls)
+	}
+
+	err = Record(context.Background(), path, time.Second, time.Minute, func(ctx context.Context) (Sample, error) {
+		return Sample{}, errors.New("unauthorized")
+	})
+	if err == nil {
+		t.Errorf("Record() went on after the first sample failed")
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/cache"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+/*
+List HorizontalPodAutoscalers,
+Returns list.items of HorizontalPodAutoscalers
+*/
+func GetHorizontalPodAutoscalers(namespace *string) (*autoscalingv2.HorizontalPodAutoscalerList, error) {
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.HorizontalPodAutoscalers(*namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &autoscalingv2.HorizontalPodAutoscalerList{Items: items}, nil
+}
+
+// Find the HPA scaling a workload, nil if there is none
+func FindForTarget(hpas []autoscalingv2.HorizontalPodAutoscaler, kind, namespace, name string) *autoscalingv2.HorizontalPodAutoscaler {
+	for i, h := range hpas {
+		ref := h.Spec.ScaleTargetRef
+		if h.Namespace == namespace && ref.Kind == kind && ref.Name == name {
+			return &hpas[i]
+		}
+	}
+	return nil
+}
+
+// Get the minimum replicas, defaults to 1
+func MinReplicas(h *autoscalingv2.HorizontalPodAutoscaler) int32 {
+	if h.Spec.MinReplicas != nil {
+		return *h.Spec.MinReplicas
+	}
+	return 1
+}
+
+// Returns true if the HPA can not scale up any further
+func PinnedAtMax(h *autoscalingv2.HorizontalPodAutoscaler) bool {
+	return h.Status.CurrentReplicas >= h.Spec.MaxReplicas
+}
+
+// Get the target cpu utilization in percent of requests, if the HPA scales on it
+func CPUTargetUtilization(h *autoscalingv2.HorizontalPodAutoscaler) (int32, bool) {
+	for _, m := range h.Spec.Metrics {
+		if m.Type == autoscalingv2.ResourceMetricSourceType && m.Resource != nil && m.Resource.Name == v1.ResourceCPU &&
+			m.Resource.Target.Type == autoscalingv2.UtilizationMetricType && m.Resource.Target.AverageUtilization != nil {
+			return *m.Resource.Target.AverageUtilization, true
+		}
+	}
+	return 0, false
+}
+
+func targetString(t autoscalingv2.MetricTarget) string {
+	switch {
+	case t.AverageUtilization != nil:
+		return strconv.Itoa(int(*t.AverageUtilization)) + "%"
+	case t.AverageValue != nil:
+		return t.AverageValue.String()
+	case t.Value != nil:
+		return t.Value.String()
+	}
+	return "<unknown>"
+}
+
+func currentString(c *autoscalingv2.MetricValueStatus) string {
+	switch {
+	case c == nil:
+		return "<unknown>"
+	case c.AverageUtilization != nil:
+		return strconv.Itoa(int(*c.AverageUtilization)) + "%"
+	case c.AverageValue != nil:
+		return c.AverageValue.String()
+	case c.Value != nil:
+		return c.Value.String()
+	}
+	return "<unknown>"
+}
+
+// Get the current status of a metric the HPA scales on, nil if not reported yet
+func findCurrent(h *autoscalingv2.HorizontalPodAutoscaler, spec autoscalingv2.MetricSpec) *autoscalingv2.MetricValueStatus {
+	for _, s := range h.Status.CurrentMetrics {
+		if s.Type != spec.Type {
+			continue
+		}
+		switch {
+		case s.Resource != nil && spec.Resource != nil && s.Resource.Name == spec.Resource.Name:
+			return &s.Resource.Current
+		case s.ContainerResource != nil && spec.ContainerResource != nil && s.ContainerResource.Name == spec.ContainerResource.Name && s.ContainerResource.Container == spec.ContainerResource.Container:
+			return &s.ContainerResource.Current
+		case s.Pods != nil && spec.Pods != nil && s.Pods.Metric.Name == spec.Pods.Metric.Name:
+			return &s.Pods.Current
+		case s.Object != nil && spec.Object != nil && s.Object.Metric.Name == spec.Object.Metric.Name:
+			return &s.Object.Current
+		case s.External != nil && spec.External != nil && s.External.Metric.Name == spec.External.Metric.Name:
+			return &s.External.Current
+		}
+	}
+	return nil
+}
+
+// Format the metrics as current/target, e.g. cpu:45%/70%,memory:60%/80%
+func FormatMetrics(h *autoscalingv2.HorizontalPodAutoscaler) string {
+	var metrics []string
+	for _, m := range h.Spec.Metrics {
+		var name string
+		var target autoscalingv2.MetricTarget
+		switch {
+		case m.Resource != nil:
+			name, target = string(m.Resource.Name), m.Resource.Target
+		case m.ContainerResource != nil:
+			name, target = m.ContainerResource.Container+"/"+string(m.ContainerResource.Name), m.ContainerResource.Target
+		case m.Pods != nil:
+			name, target = m.Pods.Metric.Name, m.Pods.Target
+		case m.Object != nil:
+			name, target = m.Object.Metric.Name, m.Object.Target
+		case m.External != nil:
+			name, target = m.External.Metric.Name, m.External.Target
+		default:
+			continue
+		}
+		metrics = append(metrics, name+":"+currentString(findCurrent(h, m))+"/"+targetString(target))
+	}
+	if len(metrics) == 0 {
+		return "-"
+	}
+	return strings.Join(metrics, ",")
+}
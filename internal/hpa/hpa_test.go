@@ -0,0 +1,130 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hpa
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func testHPA(current, max int32, metrics []autoscalingv2.MetricSpec, currentMetrics []autoscalingv2.MetricStatus) autoscalingv2.HorizontalPodAutoscaler {
+	return autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MaxReplicas:    max,
+			Metrics:        metrics,
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: current, CurrentMetrics: currentMetrics},
+	}
+}
+
+func cpuMetric(target int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   v1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: int32Ptr(target)},
+		},
+	}
+}
+
+func TestFindForTarget(t *testing.T) {
+	hpas := []autoscalingv2.HorizontalPodAutoscaler{testHPA(1, 3, nil, nil)}
+	tests := []struct {
+		kind, namespace, name string
+		found                 bool
+	}{
+		{"Deployment", "app", "web", true},
+		{"StatefulSet", "app", "web", false},
+		{"Deployment", "other", "web", false},
+		{"Deployment", "app", "api", false},
+	}
+	for _, tt := range tests {
+		if got := FindForTarget(hpas, tt.kind, tt.namespace, tt.name) != nil; got != tt.found {
+			t.Errorf("FindForTarget(%s, %s, %s) found = %v, want %v", tt.kind, tt.namespace, tt.name, got, tt.found)
+		}
+	}
+}
+
+func TestPinnedAtMax(t *testing.T) {
+	tests := []struct {
+		current, max int32
+		want         bool
+	}{
+		{3, 3, true},
+		{2, 3, false},
+	}
+	for _, tt := range tests {
+		h := testHPA(tt.current, tt.max, nil, nil)
+		if got := PinnedAtMax(&h); got != tt.want {
+			t.Errorf("PinnedAtMax(%d/%d) = %v, want %v", tt.current, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestCPUTargetUtilization(t *testing.T) {
+	h := testHPA(1, 3, []autoscalingv2.MetricSpec{cpuMetric(70)}, nil)
+	if got, ok := CPUTargetUtilization(&h); !ok || got != 70 {
+		t.Errorf("CPUTargetUtilization() = %d, %v, want 70, true", got, ok)
+	}
+	h = testHPA(1, 3, nil, nil)
+	if _, ok := CPUTargetUtilization(&h); ok {
+		t.Errorf("CPUTargetUtilization() without cpu metric ok = true, want false")
+	}
+}
+
+func TestFormatMetrics(t *testing.T) {
+	requests := resource.MustParse("100")
+	pods := autoscalingv2.MetricSpec{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{Name: "requests_per_second"},
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &requests},
+		},
+	}
+	current := []autoscalingv2.MetricStatus{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricStatus{
+			Name:    v1.ResourceCPU,
+			Current: autoscalingv2.MetricValueStatus{AverageUtilization: int32Ptr(45)},
+		},
+	}}
+
+	tests := []struct {
+		name string
+		hpa  autoscalingv2.HorizontalPodAutoscaler
+		want string
+	}{
+		{"no metrics", testHPA(1, 3, nil, nil), "-"},
+		{"cpu", testHPA(1, 3, []autoscalingv2.MetricSpec{cpuMetric(70)}, current), "cpu:45%/70%"},
+		{"not reported yet", testHPA(1, 3, []autoscalingv2.MetricSpec{cpuMetric(70), pods}, current), "cpu:45%/70%,requests_per_second:<unknown>/100"},
+	}
+	for _, tt := range tests {
+		if got := FormatMetrics(&tt.hpa); got != tt.want {
+			t.Errorf("%s: FormatMetrics() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
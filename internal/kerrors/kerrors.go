@@ -0,0 +1,97 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kerrors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// Exit codes of the cli, 1 is any other error
+const (
+	ExitError              = 1
+	ExitAuth               = 3
+	ExitUnreachable        = 4
+	ExitMetricsUnavailable = 5
+	ExitNotFound           = 6
+	ExitAuditFailed        = 7
+)
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrMetricsUnavailable = errors.New("metrics API is not available, is metrics-server installed?")
+	// The audit ran and has findings at or above --fail-on
+	ErrAuditFailed = errors.New("audit findings at or above --fail-on")
+)
+
+// Error for an object kshow looked up by name, e.g. deployment foo not found
+func NotFound(kind, name string) error {
+	return fmt.Errorf("%s %s %w", kind, name, ErrNotFound)
+}
+
+/*
+Mark an error of the metrics API as ErrMetricsUnavailable,
+the API is not registered (404) or metrics-server is down (503)
+*/
+func Metrics(err error) error {
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return fmt.Errorf("%w: %v", ErrMetricsUnavailable, err)
+	}
+	return err
+}
+
+func isUnreachable(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, rest.ErrNotInCluster) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err)
+}
+
+// Get the exit code for an error, 0 if there is none
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrAuditFailed):
+		return ExitAuditFailed
+	case errors.Is(err, ErrMetricsUnavailable):
+		return ExitMetricsUnavailable
+	case apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err):
+		return ExitAuth
+	case errors.Is(err, ErrNotFound) || apierrors.IsNotFound(err):
+		return ExitNotFound
+	case isUnreachable(err):
+		return ExitUnreachable
+	}
+	return ExitError
+}
+
+// Describe an error in one line for the user
+func Describe(err error) string {
+	switch ExitCode(err) {
+	case ExitAuth:
+		return "authentication failed: " + err.Error()
+	case ExitUnreachable:
+		return "cluster unreachable: " + err.Error()
+	}
+	return err.Error()
+}
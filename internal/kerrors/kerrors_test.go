@@ -0,0 +1,63 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kerrors
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"syscall"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestExitCode(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"none", nil, 0},
+		{"other", errors.New("boom"), ExitError},
+		{"audit failed", ErrAuditFailed, ExitAuditFailed},
+		{"unauthorized", apierrors.NewUnauthorized("Unauthorized"), ExitAuth},
+		{"forbidden", apierrors.NewForbidden(pods, "", errors.New("rbac")), ExitAuth},
+		{"api not found", apierrors.NewNotFound(pods, "web"), ExitNotFound},
+		{"not found", NotFound("deployment", "web"), ExitNotFound},
+		{"wrapped not found", fmt.Errorf("rollout: %w", NotFound("deployment", "web")), ExitNotFound},
+		{"connection refused", &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: syscall.ECONNREFUSED}, ExitUnreachable},
+		{"no config", rest.ErrNotInCluster, ExitUnreachable},
+		{"metrics not registered", Metrics(apierrors.NewNotFound(schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}, "")), ExitMetricsUnavailable},
+		{"metrics down", Metrics(apierrors.NewServiceUnavailable("metrics-server")), ExitMetricsUnavailable},
+		{"metrics forbidden", Metrics(apierrors.NewForbidden(pods, "", errors.New("rbac"))), ExitAuth},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("%s: ExitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestNotFoundMessage(t *testing.T) {
+	if got, want := NotFound("deployment", "web").Error(), "deployment web not found"; got != want {
+		t.Errorf("NotFound() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/sam0392in/kshow/internal/kerrors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+)
+
+// Group version served by metrics-server
+const metricsGroupVersion = "metrics.k8s.io/v1beta1"
+
+// Shown in place of usage when the metrics API is not available
+const NotAvailable = "n/a"
+
+// How to get usage columns back when the metrics API is not available
+const Hint = "metrics.k8s.io is not available, usage is shown as " + NotAvailable + ". Install metrics-server with:\n" +
+	"  kubectl apply -f https://github.com/kubernetes-sigs/metrics-server/releases/latest/download/components.yaml"
+
+/*
+Check with discovery that the metrics API serves pod metrics,
+it is missing when metrics-server is not installed (404) or not ready (503)
+*/
+func HasMetricsAPI(d discovery.DiscoveryInterface) (bool, error) {
+	resources, err := d.ServerResourcesForGroupVersion(metricsGroupVersion)
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == "pods" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Check that the metrics source, the metrics API of the cluster by default, is available
+func Available() (bool, error) {
+	return source.Available()
+}
+
+// Fail with ErrMetricsUnavailable if the metrics API is not available, for --require-metrics
+func Require() error {
+	ok, err := Available()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return kerrors.ErrMetricsUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHasMetricsAPI(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		want      bool
+	}{
+		{"not installed", nil, false},
+		{"pods served", []*metav1.APIResourceList{{GroupVersion: "metrics.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "nodes"}, {Name: "pods"}}}}, true},
+		{"no pod metrics", []*metav1.APIResourceList{{GroupVersion: "metrics.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "nodes"}}}}, false},
+		{"other group", []*metav1.APIResourceList{{GroupVersion: "custom.metrics.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "pods"}}}}, false},
+	}
+	for _, tt := range tests {
+		d := fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+		d.Resources = tt.resources
+		got, err := HasMetricsAPI(d)
+		if err != nil {
+			t.Fatalf("%s: HasMetricsAPI() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: HasMetricsAPI() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
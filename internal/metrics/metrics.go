@@ -28,78 +28,66 @@ package metrics
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
-	"regexp"
 	"strconv"
-	"text/tabwriter"
+	"strings"
 
+	k8sclient "github.com/sam0392in/kshow/internal/client"
 	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/hpa"
 	"github.com/sam0392in/kshow/internal/node"
 	"github.com/sam0392in/kshow/internal/pod"
-	"go.uber.org/zap"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"github.com/sam0392in/kshow/internal/style"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 var (
-	logger      *zap.Logger
 	lineBreaker string
 )
 
+// Difference between HPA cpu target and actual utilization which is flagged
+const hpaMismatchPoints = 20
+
 func init() {
-	logger, _ = zap.NewProduction()
 	lineBreaker = "--------------------------------------------------------------------------------------------------------------------------------------------------------"
 
 }
 
-func client(namespace *string) (*metricsv.Clientset, error) {
-	var kubeconfig string
-	if home := homedir.HomeDir(); home != "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-	_, err := os.Stat(kubeconfig)
-	config, _ := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	clientset, err := metricsv.NewForConfig(config)
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-	return clientset, err
+func client() (*metricsv.Clientset, error) {
+	return k8sclient.GetMetricsClient()
 }
 
 // Get total CPU and MEM of the cluster
-func GetTotalClusterResources() (float64, float64) {
+func GetTotalClusterResources() (float64, float64, error) {
 	var cpu, mem float64
 	nodes, err := node.ListNodes()
 	if err != nil {
-		logger.Error(err.Error())
+		return 0, 0, err
 	}
 
 	for _, n := range nodes {
 		cpu += n.Status.Allocatable.Cpu().AsApproximateFloat64()
 		mem += (n.Status.Allocatable.Memory().AsApproximateFloat64()) / 1048859000
 	}
-	return cpu, mem
+	return cpu, mem, nil
 }
 
 // Get total CPU and MEM of the namespace
-func GetTotalNamespaceResources(namespace string) (float64, float64) {
+func GetTotalNamespaceResources(namespace string) (float64, float64, error) {
 	var (
 		nsCPU, nsMEM float64
 	)
 	nsCPU = 0
 	nsMEM = 0
-	clientset, _ := client(&namespace)
-	podMetricsList, err := clientset.MetricsV1beta1().PodMetricses(namespace).List(context.TODO(), metav1.ListOptions{})
+	podMetricsList, err := GetPodMetrics(&namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return 0, 0, err
 	}
 	pods, err := pod.GetPods(&namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return 0, 0, err
 	}
 
 	// for _, p := range pods.Items {
@@ -112,56 +100,68 @@ func GetTotalNamespaceResources(namespace string) (float64, float64) {
 	// 		mem := c.Usage.Memory().Value() / 1048859
 	// 	}
 	// }
+	index := pod.NewPodIndex(pods.Items)
 	for _, m := range podMetricsList.Items {
-		for _, p := range pods.Items {
-			var (
-				cpu, mem, requestedCPU, requestedMem float64
-			)
-			if m.Name == p.Name {
-				for _, c := range m.Containers {
-					for _, c1 := range p.Spec.Containers {
-						if c.Name == c1.Name {
-							requestedCPU = c1.Resources.Requests.Cpu().AsApproximateFloat64()
-							requestedMem = c1.Resources.Requests.Memory().AsApproximateFloat64() / 1048859000
-							break
-						}
-					}
-					cpu = float64(c.Usage.Cpu().AsApproximateFloat64())
-					mem = float64(c.Usage.Memory().AsApproximateFloat64() / 1048859000)
-
-				}
-				// CPU and Memory will be taken in account which ever is higher of Requested VS Current
-				if cpu > requestedCPU {
-					nsCPU += cpu
-				} else {
-					nsCPU += requestedCPU
-				}
-
-				if mem > requestedMem {
-					nsMEM += mem
-				} else {
-					nsMEM += requestedMem
+		p := index.Get(m.Namespace, m.Name)
+		if p == nil {
+			continue
+		}
+		var (
+			cpu, mem, requestedCPU, requestedMem float64
+		)
+		for _, c := range m.Containers {
+			for _, c1 := range p.Spec.Containers {
+				if c.Name == c1.Name {
+					requestedCPU = c1.Resources.Requests.Cpu().AsApproximateFloat64()
+					requestedMem = c1.Resources.Requests.Memory().AsApproximateFloat64() / 1048859000
+					break
 				}
 			}
+			cpu = float64(c.Usage.Cpu().AsApproximateFloat64())
+			mem = float64(c.Usage.Memory().AsApproximateFloat64() / 1048859000)
+
+		}
+		// CPU and Memory will be taken in account which ever is higher of Requested VS Current
+		if cpu > requestedCPU {
+			nsCPU += cpu
+		} else {
+			nsCPU += requestedCPU
+		}
+
+		if mem > requestedMem {
+			nsMEM += mem
+		} else {
+			nsMEM += requestedMem
 		}
 	}
-	return nsCPU, nsMEM
+	return nsCPU, nsMEM, nil
 }
 
-// Get container resource usage
-func PrintContainerMetrics(namespace string) {
-	clientset, err := client(&namespace)
-	podMetricsList, err := clientset.MetricsV1beta1().PodMetricses(namespace).List(context.TODO(), metav1.ListOptions{})
+/*
+Print cluster and namespace totals above the container usage,
+namespace consumption needs usage and is n/a without the metrics API
+*/
+func PrintResourceHeader(namespace string, withUsage bool) error {
+	// Get Total Cluster stats
+	totalCPU, totalMem, err := GetTotalClusterResources()
 	if err != nil {
-		logger.Error(err.Error())
+		return err
 	}
-	pods, err := pod.GetPods(&namespace)
 
-	// Get Total Cluster stats
-	totalCPU, totalMem := GetTotalClusterResources()
+	if !withUsage {
+		fmt.Println(lineBreaker)
+		fmt.Println("Cluster Stats: \t\tTotal CPU: " + strconv.Itoa(int(totalCPU)) + " Cores\t\tTotal Memory: " + strconv.Itoa(int(totalMem)) + " GB")
+		fmt.Println("Namespace Stats: \tConsumed CPU: " + NotAvailable + "\t\tConsumed Memory: " + NotAvailable)
+		fmt.Println("% Stats: \t\tCPU: " + NotAvailable + "\t\t\tMemory: " + NotAvailable)
+		fmt.Println(lineBreaker)
+		return nil
+	}
 
 	// Get Total NS Stats
-	nsCPU, nsMem := GetTotalNamespaceResources(namespace)
+	nsCPU, nsMem, err := GetTotalNamespaceResources(namespace)
+	if err != nil {
+		return err
+	}
 
 	// Get % Stats
 	perCPU := (nsCPU / totalCPU) * 100
@@ -173,59 +173,41 @@ func PrintContainerMetrics(namespace string) {
 	fmt.Println("Namespace Stats: \tConsumed CPU: " + strconv.Itoa(int(nsCPU)) + " Cores\t\tConsumed Memory: " + strconv.Itoa(int(nsMem)) + " GB")
 	fmt.Println("% Stats: \t\tCPU: " + fmt.Sprintf("%.2f", perCPU) + " %\t\t\tMemory: " + fmt.Sprintf("%.2f", perMEM) + " %")
 	fmt.Println(lineBreaker)
+	return nil
+}
 
-	// Container stats
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "NAMESPACE\t\tPOD\t\tCONTAINER\t\tCURRENT-CPU\t\tREQ-CPU\t\tLIMIT-CPU\t\tCURRENT-MEM\t\tREQ-MEM\t\tLIMIT-MEM")
+// Get Pod resource usage from the metrics source
+func GetPodMetrics(namespace *string) (*v1beta1.PodMetricsList, error) {
+	return source.PodMetrics(context.TODO(), *namespace)
+}
 
-	for _, m := range podMetricsList.Items {
-		for _, p := range pods.Items {
-			var (
-				cpu                                            float32
-				requestedCPU, requestedMem, LimitCPU, LimitMem string
-			)
-			if m.Name == p.Name {
-				cpu = 0
-				for _, c := range m.Containers {
-					for _, c1 := range p.Spec.Containers {
-						if c.Name == c1.Name {
-							requestedCPU = c1.Resources.Requests.Cpu().String()
-							requestedMem = c1.Resources.Requests.Memory().String()
-							LimitCPU = c1.Resources.Limits.Cpu().String()
-							LimitMem = c1.Resources.Limits.Memory().String()
-							break
-						}
-					}
-					cpu = float32(c.Usage.Cpu().MilliValue())
-					mem := c.Usage.Memory().Value() / 1048859
-					data := m.Namespace + "\t\t" + m.Name + "\t\t" + c.Name + "\t\t" + strconv.Itoa(int(cpu)) + "m" + "\t\t" + requestedCPU + "\t\t" + LimitCPU + "\t\t" + strconv.Itoa(int(mem)) + "Mi" + "\t\t" + requestedMem + "\t\t" + LimitMem
-					fmt.Fprintln(w, data)
-				}
-			}
-		}
-	}
-	w.Flush()
+// Get Node resource usage from the metrics source
+func GetNodeMetrics() (*v1beta1.NodeMetricsList, error) {
+	return source.NodeMetrics(context.TODO())
 }
 
-// Get Pod resource usage
-func getPodMetrics(namespace *string) (*v1beta1.PodMetricsList, error) {
-	clientset, _ := client(namespace)
-	podMetricsList, err := clientset.MetricsV1beta1().PodMetricses(*namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		logger.Error(err.Error())
+// Print the usage of every pod, or its pods with usage n/a without the metrics API
+func PrintPodMetrics(namespace string, withUsage bool) error {
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\t\tPOD\tCPU\t\tMEMORY")
+
+	if !withUsage {
+		pods, err := pod.GetPods(&namespace)
+		if err != nil {
+			return err
+		}
+		for _, p := range pods.Items {
+			fmt.Fprintln(w, p.Namespace+"\t\t"+p.Name+"\t"+NotAvailable+"\t\t"+NotAvailable)
+		}
+		w.Flush()
+		return nil
 	}
-	return podMetricsList, err
-}
 
-func PrintPodMetrics(namespace string) {
-	podMetrics, err := getPodMetrics(&namespace)
+	podMetrics, err := GetPodMetrics(&namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return err
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "NAMESPACE\t\tPOD\tCPU\t\tMEMORY")
-
 	for _, m := range podMetrics.Items {
 		var (
 			cpu float32
@@ -246,66 +228,133 @@ func PrintPodMetrics(namespace string) {
 	}
 
 	w.Flush()
+	return nil
 }
 
-// Get Deployment resource metrics
-func GetDeploymentsMetrics(namespace string) {
-	deployments, err := deployment.GetDeployments(&namespace)
+/*
+Flag HPAs pinned at max replicas, or whose cpu target differs from the actual
+utilization against requests by more than hpaMismatchPoints percentage points
+*/
+func getHPAFlag(h *autoscalingv2.HorizontalPodAutoscaler, reqcpu, currcpu int) string {
+	if h == nil {
+		return "-"
+	}
+	var flags []string
+	if hpa.PinnedAtMax(h) {
+		flags = append(flags, "PINNED-AT-MAX")
+	}
+	if target, ok := hpa.CPUTargetUtilization(h); ok && reqcpu > 0 {
+		actual := currcpu * 100 / reqcpu
+		if actual-int(target) > hpaMismatchPoints || int(target)-actual > hpaMismatchPoints {
+			flags = append(flags, "CPU-TARGET-MISMATCH("+strconv.Itoa(actual)+"%/"+strconv.Itoa(int(target))+"%)")
+		}
+	}
+	if len(flags) == 0 {
+		return "-"
+	}
+	return strings.Join(flags, ",")
+}
 
+// Get the metrics of the pods of a deployment, podMetrics is keyed by namespace/pod
+func deploymentPodMetrics(d appsv1.Deployment, index *pod.PodIndex, podMetrics map[string]*v1beta1.PodMetrics) []*v1beta1.PodMetrics {
+	var owned []*v1beta1.PodMetrics
+	for _, p := range index.ForWorkload(d.Namespace, "Deployment/"+d.Name) {
+		if m, ok := podMetrics[p.Namespace+"/"+p.Name]; ok {
+			owned = append(owned, m)
+		}
+	}
+	return owned
+}
+
+/*
+Get Deployment resource metrics,
+without the metrics API requests are taken from the template for the current replicas and usage is n/a
+*/
+func GetDeploymentsMetrics(namespace string, withUsage bool) error {
+	deployments, err := deployment.GetDeployments(&namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return err
 	}
 
-	podmetrics, err := getPodMetrics(&namespace)
+	// pods are indexed by their deployment once instead of matching names per deployment
+	index := pod.NewPodIndex(nil)
+	podmetrics := make(map[string]*v1beta1.PodMetrics)
+	if withUsage {
+		pods, err := pod.GetPods(&namespace)
+		if err != nil {
+			return err
+		}
+		index = pod.NewPodIndex(pods.Items)
+		metricsList, err := GetPodMetrics(&namespace)
+		if err != nil {
+			return err
+		}
+		for i := range metricsList.Items {
+			m := &metricsList.Items[i]
+			podmetrics[m.Namespace+"/"+m.Name] = m
+		}
+	}
 
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "NAMESPACE\tDEPLOYMENT\tREQ-CPU\tCURRENT-CPU\t\tREQ-MEM\tCURRENT-MEM")
+	hpaList, err := hpa.GetHorizontalPodAutoscalers(&namespace)
+	if err != nil {
+		return err
+	}
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\tDEPLOYMENT\tREQ-CPU\tCURRENT-CPU\t\tREQ-MEM\tCURRENT-MEM\tHPA-FLAG")
 
 	for _, deploy := range deployments.Items {
 		var (
 			reqcpu, currcpu, reqmem, currmem int
 		)
 
-		for _, pod := range podmetrics.Items {
-			podDeploymentName := deployment.GetDeploymentFromPod(pod.Name)
-			re := regexp.MustCompile("^" + deploy.Name + "$")
-			exactMatch := re.MatchString(podDeploymentName)
-
-			if exactMatch {
-				var (
-					mem          int64
-					cpu          float32
-					dcmem, dccpu int64
-				)
-				for _, c := range pod.Containers {
-					//container current cpu
-					a := c.Usage.Cpu()
-					cpu += float32(a.MilliValue())
-					//container current mem
-					b := c.Usage.Memory().Value()
-					mem += b
-					mem = mem / 1048859
-					//container requested cpu
-				}
+		for _, m := range deploymentPodMetrics(deploy, index, podmetrics) {
+			var (
+				mem          int64
+				cpu          float32
+				dcmem, dccpu int64
+			)
+			for _, c := range m.Containers {
+				//container current cpu
+				a := c.Usage.Cpu()
+				cpu += float32(a.MilliValue())
+				//container current mem
+				b := c.Usage.Memory().Value()
+				mem += b
+				mem = mem / 1048859
+				//container requested cpu
+			}
 
-				for _, dc := range deploy.Spec.Template.Spec.Containers {
-					// deploymentcontainer requested cpu
-					a := dc.Resources.Requests.Memory().Value()
-					dcmem += a
-					b := dc.Resources.Requests.Cpu().MilliValue()
-					dccpu += b
+			for _, dc := range deploy.Spec.Template.Spec.Containers {
+				// deploymentcontainer requested cpu
+				a := dc.Resources.Requests.Memory().Value()
+				dcmem += a
+				b := dc.Resources.Requests.Cpu().MilliValue()
+				dccpu += b
 
-				}
+			}
+
+			currcpu += int(cpu)
+			currmem += int(mem)
+			reqcpu += int(dccpu)
+			reqmem += int(dcmem) / 1048576
+		}
 
-				currcpu += int(cpu)
-				currmem += int(mem)
-				reqcpu += int(dccpu)
-				reqmem += int(dcmem) / 1048576
+		// The cpu target mismatch needs usage, only PINNED-AT-MAX is flagged without it
+		h := hpa.FindForTarget(hpaList.Items, "Deployment", deploy.Namespace, deploy.Name)
+		hpaFlag := getHPAFlag(h, reqcpu, currcpu)
+		currentCPU, currentMem := strconv.Itoa(currcpu)+"m", strconv.Itoa(currmem)+"Mi"
+		if !withUsage {
+			for _, dc := range deploy.Spec.Template.Spec.Containers {
+				reqcpu += int(dc.Resources.Requests.Cpu().MilliValue()) * int(deploy.Status.Replicas)
+				reqmem += int(dc.Resources.Requests.Memory().Value()/1048576) * int(deploy.Status.Replicas)
 			}
+			currentCPU, currentMem = NotAvailable, NotAvailable
 		}
 
-		data := deploy.Namespace + "\t" + deploy.Name + "\t" + strconv.Itoa(reqcpu) + "m\t" + strconv.Itoa(currcpu) + "m\t\t" + strconv.Itoa(reqmem) + "Mi\t" + strconv.Itoa(currmem) + "Mi"
+		data := deploy.Namespace + "\t" + deploy.Name + "\t" + strconv.Itoa(reqcpu) + "m\t" + currentCPU + "\t\t" + strconv.Itoa(reqmem) + "Mi\t" + currentMem + "\t" + hpaFlag
 		fmt.Fprintln(w, data)
 	}
 	w.Flush()
+	return nil
 }
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/sam0392in/kshow/internal/pod"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestDeploymentPodMetrics(t *testing.T) {
+	controller := true
+	replicaSetPod := func(name, replicaSet, hash string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "app",
+			Name:            name,
+			Labels:          map[string]string{"pod-template-hash": hash},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet, Controller: &controller}},
+		}}
+	}
+	pods := []v1.Pod{
+		replicaSetPod("a.b-5d8f7c9b6-x2x9p", "a.b-5d8f7c9b6", "5d8f7c9b6"),
+		replicaSetPod("axb-6f4d8b7c5-k8s9q", "axb-6f4d8b7c5", "6f4d8b7c5"),
+		replicaSetPod("axb-6f4d8b7c5-m2n4r", "axb-6f4d8b7c5", "6f4d8b7c5"),
+	}
+	podMetrics := make(map[string]*v1beta1.PodMetrics)
+	for _, p := range pods {
+		podMetrics[p.Namespace+"/"+p.Name] = &v1beta1.PodMetrics{ObjectMeta: p.ObjectMeta}
+	}
+	// a pod without metrics yet
+	pods = append(pods, replicaSetPod("axb-6f4d8b7c5-zzzzz", "axb-6f4d8b7c5", "6f4d8b7c5"))
+	index := pod.NewPodIndex(pods)
+
+	tests := []struct {
+		deployment string
+		want       int
+	}{
+		{"a.b", 1},
+		{"axb", 2},
+		{"a", 0},
+	}
+	for _, tt := range tests {
+		d := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: tt.deployment}}
+		if got := deploymentPodMetrics(d, index, podMetrics); len(got) != tt.want {
+			t.Errorf("deploymentPodMetrics(%s) = %d pods, want %d", tt.deployment, len(got), tt.want)
+		}
+	}
+}
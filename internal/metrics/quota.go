@@ -0,0 +1,208 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/quota"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Quota usage above this percentage is flagged
+const quotaThreshold = 80.0
+
+// Live cpu and memory usage of a namespace
+type namespaceUsage struct {
+	cpu, mem resource.Quantity
+}
+
+// Sum the live usage of all pods per namespace
+func getNamespaceUsage(namespace string) (map[string]*namespaceUsage, error) {
+	podMetrics, err := GetPodMetrics(&namespace)
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[string]*namespaceUsage)
+	for _, m := range podMetrics.Items {
+		u, ok := usage[m.Namespace]
+		if !ok {
+			u = &namespaceUsage{}
+			usage[m.Namespace] = u
+		}
+		for _, c := range m.Containers {
+			u.cpu.Add(*c.Usage.Cpu())
+			u.mem.Add(*c.Usage.Memory())
+		}
+	}
+	return usage, nil
+}
+
+// Get the live usage matching a quota resource, e.g. requests.cpu is compared to cpu usage
+func liveUsageFor(name v1.ResourceName, u *namespaceUsage) (resource.Quantity, bool) {
+	if u == nil {
+		return resource.Quantity{}, false
+	}
+	switch name {
+	case v1.ResourceCPU, v1.ResourceRequestsCPU, v1.ResourceLimitsCPU:
+		return u.cpu, true
+	case v1.ResourceMemory, v1.ResourceRequestsMemory, v1.ResourceLimitsMemory:
+		return u.mem, true
+	}
+	return resource.Quantity{}, false
+}
+
+func percentOf(used, hard resource.Quantity) float64 {
+	if hard.IsZero() {
+		return 0
+	}
+	return used.AsApproximateFloat64() / hard.AsApproximateFloat64() * 100
+}
+
+// Format cpu in millicores and memory in Mi the same way as the other resource-stats tables
+func formatQuantity(name v1.ResourceName, q resource.Quantity) string {
+	switch name {
+	case v1.ResourceCPU, v1.ResourceRequestsCPU, v1.ResourceLimitsCPU:
+		return strconv.FormatInt(q.MilliValue(), 10) + "m"
+	case v1.ResourceMemory, v1.ResourceRequestsMemory, v1.ResourceLimitsMemory:
+		return strconv.FormatInt(q.Value()/1048576, 10) + "Mi"
+	}
+	return q.String()
+}
+
+// Flag a quota resource when its used or live usage is over quotaThreshold
+func quotaFlag(usedPercent, livePercent float64, hasLive bool) string {
+	if usedPercent > quotaThreshold || (hasLive && livePercent > quotaThreshold) {
+		return "OVER-" + strconv.Itoa(int(quotaThreshold)) + "%"
+	}
+	return "-"
+}
+
+// Print ResourceQuota usage against hard limits and live metrics, and LimitRange defaults
+func PrintQuotaUsage(namespace string, withUsage bool) error {
+	quotas, err := quota.GetResourceQuotas(&namespace)
+	if err != nil {
+		return err
+	}
+	usage := make(map[string]*namespaceUsage)
+	if withUsage {
+		if usage, err = getNamespaceUsage(namespace); err != nil {
+			return err
+		}
+	}
+
+	overThreshold := make(map[string]bool)
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\t\tQUOTA\t\tRESOURCE\t\tUSED\t\tHARD\t\tUSED%\t\tLIVE\t\tLIVE%\t\tFLAG")
+	for _, q := range quotas.Items {
+		var names []string
+		for name := range q.Status.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			name := v1.ResourceName(n)
+			hard := q.Status.Hard[name]
+			used := q.Status.Used[name]
+			usedPercent := percentOf(used, hard)
+
+			live, livePercent := "-", "-"
+			if !withUsage {
+				live, livePercent = NotAvailable, NotAvailable
+			}
+			l, hasLive := liveUsageFor(name, usage[q.Namespace])
+			lp := percentOf(l, hard)
+			if hasLive {
+				live = formatQuantity(name, l)
+				livePercent = fmt.Sprintf("%.1f", lp)
+			}
+			flag := quotaFlag(usedPercent, lp, hasLive)
+			if flag != "-" {
+				overThreshold[q.Namespace] = true
+			}
+
+			data := q.Namespace + "\t\t" + q.Name + "\t\t" + n + "\t\t" + formatQuantity(name, used) + "\t\t" + formatQuantity(name, hard) + "\t\t" + fmt.Sprintf("%.1f", usedPercent) + "\t\t" + live + "\t\t" + livePercent + "\t\t" + flag
+			fmt.Fprintln(w, data)
+		}
+	}
+	w.Flush()
+
+	if len(overThreshold) != 0 {
+		var namespaces []string
+		for ns := range overThreshold {
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+		fmt.Println("\nNamespaces over " + strconv.Itoa(int(quotaThreshold)) + "% of quota: " + strings.Join(namespaces, ", "))
+	}
+
+	return printLimitRangeDefaults(namespace)
+}
+
+// Print the defaults LimitRanges apply to containers without explicit requests or limits
+func printLimitRangeDefaults(namespace string) error {
+	limitRanges, err := quota.GetLimitRanges(&namespace)
+	if err != nil {
+		return err
+	}
+	if len(limitRanges.Items) == 0 {
+		return nil
+	}
+
+	fmt.Println("\n" + lineBreaker)
+	fmt.Println("LimitRange defaults applied to containers without explicit requests or limits")
+	fmt.Println(lineBreaker)
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\t\tLIMITRANGE\t\tRESOURCE\t\tDEFAULT-REQUEST\t\tDEFAULT-LIMIT\t\tMIN\t\tMAX")
+	for _, lr := range limitRanges.Items {
+		for _, data := range limitRangeRows(lr) {
+			fmt.Fprintln(w, data)
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+// Rows of the cpu and memory defaults of the container limits of a LimitRange
+func limitRangeRows(lr v1.LimitRange) []string {
+	var rows []string
+	for _, item := range lr.Spec.Limits {
+		if item.Type != v1.LimitTypeContainer {
+			continue
+		}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			rows = append(rows, lr.Namespace+"\t\t"+lr.Name+"\t\t"+string(name)+"\t\t"+quantityOrDash(item.DefaultRequest, name)+"\t\t"+quantityOrDash(item.Default, name)+"\t\t"+quantityOrDash(item.Min, name)+"\t\t"+quantityOrDash(item.Max, name))
+		}
+	}
+	return rows
+}
+
+func quantityOrDash(l v1.ResourceList, name v1.ResourceName) string {
+	if q, ok := l[name]; ok {
+		return q.String()
+	}
+	return "-"
+}
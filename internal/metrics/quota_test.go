@@ -0,0 +1,147 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		used, hard string
+		want       float64
+	}{
+		{"500m", "2", 25},
+		{"3Gi", "4Gi", 75},
+		{"1", "0", 0},
+		{"0", "10", 0},
+	}
+	for _, tt := range tests {
+		if got := percentOf(resource.MustParse(tt.used), resource.MustParse(tt.hard)); got != tt.want {
+			t.Errorf("percentOf(%s, %s) = %v, want %v", tt.used, tt.hard, got, tt.want)
+		}
+	}
+}
+
+func TestLiveUsageFor(t *testing.T) {
+	u := &namespaceUsage{cpu: resource.MustParse("1500m"), mem: resource.MustParse("2Gi")}
+	tests := []struct {
+		name   v1.ResourceName
+		want   string
+		wantOK bool
+	}{
+		{v1.ResourceRequestsCPU, "1500m", true},
+		{v1.ResourceLimitsCPU, "1500m", true},
+		{v1.ResourceLimitsMemory, "2Gi", true},
+		{v1.ResourceRequestsMemory, "2Gi", true},
+		{v1.ResourcePods, "0", false},
+	}
+	for _, tt := range tests {
+		got, ok := liveUsageFor(tt.name, u)
+		if ok != tt.wantOK || got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("liveUsageFor(%s) = %s, %v, want %s, %v", tt.name, got.String(), ok, tt.want, tt.wantOK)
+		}
+	}
+	if _, ok := liveUsageFor(v1.ResourceRequestsCPU, nil); ok {
+		t.Errorf("liveUsageFor() of a namespace without usage is ok")
+	}
+}
+
+func TestQuotaFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		used, live float64
+		hasLive    bool
+		want       string
+	}{
+		{"under", 50, 60, true, "-"},
+		{"at threshold", 80, 80, true, "-"},
+		{"used over", 80.5, 10, true, "OVER-80%"},
+		{"live over", 10, 95, true, "OVER-80%"},
+		{"live over without usage", 10, 95, false, "-"},
+	}
+	for _, tt := range tests {
+		if got := quotaFlag(tt.used, tt.live, tt.hasLive); got != tt.want {
+			t.Errorf("%s: quotaFlag() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimitRangeRows(t *testing.T) {
+	lr := v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "defaults"},
+		Spec: v1.LimitRangeSpec{Limits: []v1.LimitRangeItem{
+			{Type: v1.LimitTypePod, Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")}},
+			{
+				Type:           v1.LimitTypeContainer,
+				DefaultRequest: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("128Mi")},
+				Default:        v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+				Max:            v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			},
+		}},
+	}
+	want := []string{
+		"app\t\tdefaults\t\tcpu\t\t100m\t\t-\t\t-\t\t2",
+		"app\t\tdefaults\t\tmemory\t\t128Mi\t\t256Mi\t\t-\t\t-",
+	}
+	if got := limitRangeRows(lr); !reflect.DeepEqual(got, want) {
+		t.Errorf("limitRangeRows() = %q, want %q", got, want)
+	}
+}
+
+func TestGetNamespaceUsage(t *testing.T) {
+	container := func(name, cpu, mem string) v1beta1.ContainerMetrics {
+		return v1beta1.ContainerMetrics{Name: name, Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(mem)}}
+	}
+	list := &v1beta1.PodMetricsList{Items: []v1beta1.PodMetrics{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-1"}, Containers: []v1beta1.ContainerMetrics{container("web", "250m", "100Mi"), container("proxy", "50m", "28Mi")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-2"}, Containers: []v1beta1.ContainerMetrics{container("web", "200m", "128Mi")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "db-0"}, Containers: []v1beta1.ContainerMetrics{container("db", "1", "1Gi")}},
+	}}
+	// the fake tracker files PodMetrics under podmetricses, the client lists pods
+	client := fakemetrics.NewSimpleClientset()
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, list, nil
+	})
+	defer SetSource(GetSource())
+	SetSource(MetricsServer{Client: client})
+
+	usage, err := getNamespaceUsage("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 || usage["app"].cpu.MilliValue() != 500 || usage["app"].mem.Value() != 256<<20 || usage["db"].cpu.MilliValue() != 1000 {
+		t.Errorf("getNamespaceUsage() = %+v", usage)
+	}
+
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("metrics-server unavailable")
+	})
+	if _, err := getNamespaceUsage(""); err == nil {
+		t.Errorf("getNamespaceUsage() dropped the metrics error")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	k8sclient "github.com/sam0392in/kshow/internal/client"
+	"github.com/sam0392in/kshow/internal/kerrors"
+	"github.com/sam0392in/kshow/internal/style"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+/*
+Source is where pod and node usage is read from,
+metrics-server by default or Prometheus with --prometheus-url
+*/
+type Source interface {
+	// Check that the source serves usage, false shows usage as n/a with Hint
+	Available() (bool, error)
+	PodMetrics(ctx context.Context, namespace string) (*v1beta1.PodMetricsList, error)
+	NodeMetrics(ctx context.Context) (*v1beta1.NodeMetricsList, error)
+}
+
+// WindowSource is a Source keeping history, it summarizes usage over a window
+type WindowSource interface {
+	Source
+	ContainerWindowUsage(ctx context.Context, namespace string, window time.Duration) ([]WindowUsage, error)
+}
+
+// WindowUsage is the usage of a container over a window
+type WindowUsage struct {
+	Namespace, Pod, Container              string
+	AvgCPU, PeakCPU, AvgMemory, PeakMemory resource.Quantity
+	// Percent of cpu periods throttled, HasThrottling is false for containers without a cpu limit
+	Throttled     float64
+	HasThrottling bool
+}
+
+var source Source = MetricsServer{}
+
+// Read usage from another source than metrics-server
+func SetSource(s Source) {
+	source = s
+}
+
+// Get the source usage is read from
+func GetSource() Source {
+	return source
+}
+
+// MetricsServer reads usage from the metrics.k8s.io API, with the kshow client if Client is nil
+type MetricsServer struct {
+	Client metricsv.Interface
+}
+
+func (m MetricsServer) client() (metricsv.Interface, error) {
+	if m.Client != nil {
+		return m.Client, nil
+	}
+	return client()
+}
+
+func (m MetricsServer) Available() (bool, error) {
+	clientset, err := k8sclient.GetK8sClient()
+	if err != nil {
+		return false, err
+	}
+	return HasMetricsAPI(clientset.Discovery())
+}
+
+func (m MetricsServer) PodMetrics(ctx context.Context, namespace string) (*v1beta1.PodMetricsList, error) {
+	clientset, err := m.client()
+	if err != nil {
+		return nil, err
+	}
+	podMetricsList, err := clientset.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kerrors.Metrics(err)
+	}
+	return podMetricsList, nil
+}
+
+func (m MetricsServer) NodeMetrics(ctx context.Context) (*v1beta1.NodeMetricsList, error) {
+	clientset, err := m.client()
+	if err != nil {
+		return nil, err
+	}
+	nodeMetricsList, err := clientset.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kerrors.Metrics(err)
+	}
+	return nodeMetricsList, nil
+}
+
+// Print average and peak usage and cpu throttling of every container over the window
+func PrintWindowUsage(namespace string, window time.Duration) error {
+	ws, ok := source.(WindowSource)
+	if !ok {
+		return errors.New("metrics-server has no history, --window needs --prometheus-url or --from a kshow record file")
+	}
+	usage, err := ws.ContainerWindowUsage(context.TODO(), namespace, window)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Usage over the last " + window.String())
+	fmt.Println()
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NAMESPACE\t\tPOD\t\tCONTAINER\t\tAVG-CPU\t\tPEAK-CPU\t\tAVG-MEM\t\tPEAK-MEM\t\tTHROTTLED")
+	for _, u := range usage {
+		throttled := "-"
+		if u.HasThrottling {
+			throttled = strconv.FormatFloat(u.Throttled, 'f', 1, 64) + "%"
+		}
+		data := u.Namespace + "\t\t" + u.Pod + "\t\t" + u.Container + "\t\t" +
+			strconv.FormatInt(u.AvgCPU.MilliValue(), 10) + "m\t\t" + strconv.FormatInt(u.PeakCPU.MilliValue(), 10) + "m\t\t" +
+			strconv.FormatInt(u.AvgMemory.Value()/1048576, 10) + "Mi\t\t" + strconv.FormatInt(u.PeakMemory.Value()/1048576, 10) + "Mi\t\t" + throttled
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
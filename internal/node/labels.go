@@ -0,0 +1,78 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Capacity types kshow reports, as EKS labels them
+const (
+	OnDemand = "ON_DEMAND"
+	Spot     = "SPOT"
+)
+
+// ProviderLabels are the node labels placement is read from
+type ProviderLabels struct {
+	NodeGroup, CapacityType, InstanceType, Zone string
+	// Values of the capacity type label mapped to ON_DEMAND or SPOT, e.g. spot: SPOT for karpenter
+	CapacityTypeValues map[string]string
+}
+
+// Labels of EKS managed node groups, the default
+var EKSLabels = ProviderLabels{
+	NodeGroup:    "eks.amazonaws.com/nodegroup",
+	CapacityType: "eks.amazonaws.com/capacityType",
+	InstanceType: "node.kubernetes.io/instance-type",
+	Zone:         "topology.kubernetes.io/zone",
+}
+
+// Labels read by every command, set from the config file for other providers
+var Labels = EKSLabels
+
+// Map a value of the capacity type label to ON_DEMAND or SPOT, unmapped values are returned as they are
+func NormalizeCapacityType(value string) string {
+	if mapped, ok := Labels.CapacityTypeValues[value]; ok {
+		return mapped
+	}
+	if strings.EqualFold(value, OnDemand) || strings.EqualFold(value, Spot) {
+		return strings.ToUpper(value)
+	}
+	return value
+}
+
+// Get the node group of a node
+func GetNodeGroup(n v1.Node) string {
+	return n.Labels[Labels.NodeGroup]
+}
+
+// Get the capacity type of a node, ON_DEMAND or SPOT
+func GetCapacityType(n v1.Node) string {
+	return NormalizeCapacityType(n.Labels[Labels.CapacityType])
+}
+
+// Get the instance type of a node
+func GetInstanceType(n v1.Node) string {
+	return n.Labels[Labels.InstanceType]
+}
+
+// Get the zone of a node
+func GetZone(n v1.Node) string {
+	return n.Labels[Labels.Zone]
+}
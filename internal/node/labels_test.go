@@ -0,0 +1,52 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package node
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetCapacityType(t *testing.T) {
+	defer func() { Labels = EKSLabels }()
+
+	eks := v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"eks.amazonaws.com/capacityType": "SPOT"}}}
+	if got := GetCapacityType(eks); got != Spot {
+		t.Errorf("GetCapacityType() of an EKS node = %q, want SPOT", got)
+	}
+
+	Labels = ProviderLabels{
+		CapacityType:       "karpenter.sh/capacity-type",
+		CapacityTypeValues: map[string]string{"on-demand": OnDemand},
+	}
+	tests := []struct {
+		value, want string
+	}{
+		{"on-demand", OnDemand},
+		{"spot", Spot},
+		{"reserved", "reserved"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		n := v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"karpenter.sh/capacity-type": tt.value}}}
+		if got := GetCapacityType(n); got != tt.want {
+			t.Errorf("GetCapacityType(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// A resource requested above this percentage is exhausted, the free capacity of the other one is stranded
+const exhaustedPercent = 90.0
+
+// NodeUsage is the requested cpu (cores) and memory (bytes) of a node against its allocatable
+type NodeUsage struct {
+	Name, NodeGroup              string
+	CPURequested, CPUAllocatable float64
+	MemRequested, MemAllocatable float64
+	Pods                         int
+	// requests of daemonset pods, they can not move to another node
+	DaemonCPU, DaemonMem float64
+	// requests of every other pod on the node
+	Movable []Request
+}
+
+// Request is the cpu and memory request of a single pod
+type Request struct {
+	CPU, Mem float64
+}
+
+// GroupPacking is the result of repacking the pods of a node group
+type GroupPacking struct {
+	NodeGroup              string
+	Nodes, NodesNeeded     int
+	CPUPercent, MemPercent float64
+}
+
+func percent(used, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return used / total * 100
+}
+
+func (n NodeUsage) CPUPercent() float64 {
+	return percent(n.CPURequested, n.CPUAllocatable)
+}
+
+func (n NodeUsage) MemPercent() float64 {
+	return percent(n.MemRequested, n.MemAllocatable)
+}
+
+// Get capacity which can not be used because the other resource is exhausted, e.g. "cpu 2.50" or "mem 6.0Gi"
+func (n NodeUsage) Stranded() string {
+	cpuPercent, memPercent := n.CPUPercent(), n.MemPercent()
+	switch {
+	case memPercent >= exhaustedPercent && cpuPercent < exhaustedPercent:
+		return "cpu " + strconv.FormatFloat(n.CPUAllocatable-n.CPURequested, 'f', 2, 64)
+	case cpuPercent >= exhaustedPercent && memPercent < exhaustedPercent:
+		return "mem " + strconv.FormatFloat((n.MemAllocatable-n.MemRequested)/(1<<30), 'f', 1, 64) + "Gi"
+	}
+	return "-"
+}
+
+// Sum up pod requests per node
+func GetNodeUsage(nodes []v1.Node, pods []v1.Pod) []NodeUsage {
+	index := make(map[string]int)
+	usage := make([]NodeUsage, 0, len(nodes))
+	for i, n := range nodes {
+		index[n.Name] = i
+		usage = append(usage, NodeUsage{
+			Name:           n.Name,
+			NodeGroup:      node.GetNodeGroup(n),
+			CPUAllocatable: n.Status.Allocatable.Cpu().AsApproximateFloat64(),
+			MemAllocatable: n.Status.Allocatable.Memory().AsApproximateFloat64(),
+		})
+	}
+
+	for _, p := range pods {
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		i, ok := index[p.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		requests := pod.GetPodRequests(p.Spec)
+		cpu := requests.Cpu().AsApproximateFloat64()
+		mem := requests.Memory().AsApproximateFloat64()
+
+		u := &usage[i]
+		u.CPURequested += cpu
+		u.MemRequested += mem
+		u.Pods++
+		if isDaemonSetPod(p) {
+			u.DaemonCPU += cpu
+			u.DaemonMem += mem
+		} else {
+			u.Movable = append(u.Movable, Request{CPU: cpu, Mem: mem})
+		}
+	}
+	return usage
+}
+
+func isDaemonSetPod(p v1.Pod) bool {
+	for _, o := range p.OwnerReferences {
+		if o.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Repack the pods of each node group with first-fit-decreasing,
+pods are placed largest first on the largest nodes and a node is only opened
+when no open node fits. Daemonset pods stay on every node. Scheduling
+constraints like affinity and taints are not taken into account
+*/
+func Repack(usage []NodeUsage) []GroupPacking {
+	groups := make(map[string][]NodeUsage)
+	var names []string
+	for _, u := range usage {
+		if _, ok := groups[u.NodeGroup]; !ok {
+			names = append(names, u.NodeGroup)
+		}
+		groups[u.NodeGroup] = append(groups[u.NodeGroup], u)
+	}
+	sort.Strings(names)
+
+	var result []GroupPacking
+	for _, name := range names {
+		nodes := groups[name]
+		var (
+			requests                                       []Request
+			cpuRequested, cpuTotal, memRequested, memTotal float64
+		)
+		for _, n := range nodes {
+			requests = append(requests, n.Movable...)
+			cpuRequested += n.CPURequested
+			cpuTotal += n.CPUAllocatable
+			memRequested += n.MemRequested
+			memTotal += n.MemAllocatable
+		}
+
+		// largest node first, free capacity excludes daemonset pods
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].CPUAllocatable+nodes[i].MemAllocatable/(1<<30) > nodes[j].CPUAllocatable+nodes[j].MemAllocatable/(1<<30)
+		})
+		// largest pod first, by its dominant share of the largest node
+		sort.SliceStable(requests, func(i, j int) bool {
+			return dominantShare(requests[i], nodes[0]) > dominantShare(requests[j], nodes[0])
+		})
+
+		type bin struct{ cpu, mem float64 }
+		var bins []bin
+		next := 0
+		unplaced := false
+		for _, r := range requests {
+			placed := false
+			for b := range bins {
+				if bins[b].cpu >= r.CPU && bins[b].mem >= r.Mem {
+					bins[b].cpu -= r.CPU
+					bins[b].mem -= r.Mem
+					placed = true
+					break
+				}
+			}
+			for !placed && next < len(nodes) {
+				n := nodes[next]
+				next++
+				b := bin{cpu: n.CPUAllocatable - n.DaemonCPU, mem: n.MemAllocatable - n.DaemonMem}
+				if b.cpu >= r.CPU && b.mem >= r.Mem {
+					b.cpu -= r.CPU
+					b.mem -= r.Mem
+					placed = true
+				}
+				bins = append(bins, b)
+			}
+			if !placed {
+				unplaced = true
+			}
+		}
+
+		needed := len(bins)
+		if unplaced {
+			needed = len(nodes)
+		}
+		result = append(result, GroupPacking{
+			NodeGroup:   name,
+			Nodes:       len(nodes),
+			NodesNeeded: needed,
+			CPUPercent:  percent(cpuRequested, cpuTotal),
+			MemPercent:  percent(memRequested, memTotal),
+		})
+	}
+	return result
+}
+
+func dominantShare(r Request, n NodeUsage) float64 {
+	cpu := percent(r.CPU, n.CPUAllocatable)
+	mem := percent(r.Mem, n.MemAllocatable)
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
+
+// Print per node requests against allocatable and drainable nodes per node group
+func PrintPacking() error {
+	nodes, err := node.ListNodes()
+	if err != nil {
+		return err
+	}
+	namespace := ""
+	pods, err := pod.GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+	usage := GetNodeUsage(nodes, pods.Items)
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NODE\t\tNODEGROUP\t\tPODS\t\tREQ-CPU\t\tALLOC-CPU\t\tCPU%\t\tREQ-MEM\t\tALLOC-MEM\t\tMEM%\t\tSTRANDED")
+	for _, u := range usage {
+		data := u.Name + "\t\t" + u.NodeGroup + "\t\t" + strconv.Itoa(u.Pods) + "\t\t" +
+			strconv.FormatFloat(u.CPURequested, 'f', 2, 64) + "\t\t" + strconv.FormatFloat(u.CPUAllocatable, 'f', 2, 64) + "\t\t" + fmt.Sprintf("%.1f", u.CPUPercent()) + "\t\t" +
+			strconv.Itoa(int(u.MemRequested/1048576)) + "Mi\t\t" + strconv.Itoa(int(u.MemAllocatable/1048576)) + "Mi\t\t" + fmt.Sprintf("%.1f", u.MemPercent()) + "\t\t" + u.Stranded()
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = style.NewWriter()
+	fmt.Fprintln(w, "NODEGROUP\t\tNODES\t\tCPU%\t\tMEM%\t\tNODES-NEEDED\t\tDRAINABLE")
+	for _, g := range Repack(usage) {
+		data := g.NodeGroup + "\t\t" + strconv.Itoa(g.Nodes) + "\t\t" + fmt.Sprintf("%.1f", g.CPUPercent) + "\t\t" + fmt.Sprintf("%.1f", g.MemPercent) + "\t\t" + strconv.Itoa(g.NodesNeeded) + "\t\t" + strconv.Itoa(g.Nodes-g.NodesNeeded)
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packing
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name, nodeGroup string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"eks.amazonaws.com/nodegroup": nodeGroup}},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("16Gi"),
+		}},
+	}
+}
+
+func testPod(nodeName, cpu, mem, ownerKind string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind}}},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(mem),
+			}}}},
+		},
+	}
+}
+
+func TestRepack(t *testing.T) {
+	nodes := []v1.Node{testNode("a", "spot"), testNode("b", "spot"), testNode("c", "spot"), testNode("d", "ondemand")}
+	pods := []v1.Pod{
+		testPod("a", "100m", "256Mi", "DaemonSet"),
+		testPod("b", "100m", "256Mi", "DaemonSet"),
+		testPod("c", "100m", "256Mi", "DaemonSet"),
+		testPod("a", "1", "4Gi", "ReplicaSet"),
+		testPod("b", "1", "4Gi", "ReplicaSet"),
+		testPod("c", "1", "4Gi", "ReplicaSet"),
+		testPod("d", "3", "2Gi", "ReplicaSet"),
+	}
+
+	groups := Repack(GetNodeUsage(nodes, pods))
+	if len(groups) != 2 {
+		t.Fatalf("got %d node groups, want 2", len(groups))
+	}
+	if g := groups[1]; g.NodeGroup != "spot" || g.Nodes != 3 || g.NodesNeeded != 1 {
+		t.Errorf("spot = %+v, want 3 nodes and 1 needed", g)
+	}
+	if g := groups[0]; g.NodeGroup != "ondemand" || g.NodesNeeded != 1 {
+		t.Errorf("ondemand = %+v, want 1 needed", g)
+	}
+}
+
+func TestStranded(t *testing.T) {
+	usage := GetNodeUsage([]v1.Node{testNode("a", "spot")}, []v1.Pod{testPod("a", "1", "15Gi", "ReplicaSet")})
+	if got, want := usage[0].Stranded(), "cpu 3.00"; got != want {
+		t.Errorf("Stranded() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"github.com/sam0392in/kshow/internal/cache"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+/*
+List PodDisruptionBudgets,
+Returns list.items of PodDisruptionBudgets
+*/
+func GetPodDisruptionBudgets(namespace *string) (*policyv1.PodDisruptionBudgetList, error) {
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.PodDisruptionBudgets(*namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &policyv1.PodDisruptionBudgetList{Items: items}, nil
+}
+
+/*
+Returns true if the PDB selects pods with the given labels in the given namespace,
+a nil selector selects no pod and an empty one every pod of the namespace
+*/
+func Matches(pdb policyv1.PodDisruptionBudget, namespace string, podLabels map[string]string) bool {
+	if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(podLabels))
+}
+
+// Get the PDBs selecting pods with the given labels
+func FindForPods(pdbs []policyv1.PodDisruptionBudget, namespace string, podLabels map[string]string) []policyv1.PodDisruptionBudget {
+	var matched []policyv1.PodDisruptionBudget
+	for _, p := range pdbs {
+		if Matches(p, namespace, podLabels) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
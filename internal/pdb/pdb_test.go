@@ -0,0 +1,70 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPDB(namespace string, selector *metav1.LabelSelector) policyv1.PodDisruptionBudget {
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: namespace},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+	}
+}
+
+func TestMatches(t *testing.T) {
+	web := map[string]string{"app": "web", "tier": "frontend"}
+	tests := []struct {
+		name      string
+		pdb       policyv1.PodDisruptionBudget
+		namespace string
+		want      bool
+	}{
+		{"nil selector", testPDB("app", nil), "app", false},
+		{"empty selector", testPDB("app", &metav1.LabelSelector{}), "app", true},
+		{"match labels", testPDB("app", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}), "app", true},
+		{"other labels", testPDB("app", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}), "app", false},
+		{"match expressions", testPDB("app", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "edge"}},
+		}}), "app", true},
+		{"failing expression", testPDB("app", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"frontend"}},
+		}}), "app", false},
+		{"other namespace", testPDB("app", &metav1.LabelSelector{}), "db", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.pdb, tt.namespace, web); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindForPods(t *testing.T) {
+	pdbs := []policyv1.PodDisruptionBudget{
+		testPDB("app", nil),
+		testPDB("app", &metav1.LabelSelector{}),
+		testPDB("app", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}),
+		testPDB("db", &metav1.LabelSelector{}),
+	}
+	if got := FindForPods(pdbs, "app", map[string]string{"app": "web"}); len(got) != 2 {
+		t.Errorf("FindForPods() = %d PDBs, want the catch-all and the app=web one", len(got))
+	}
+}
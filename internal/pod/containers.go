@@ -0,0 +1,138 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Container types shown in the TYPE column
+const (
+	ContainerTypeApp       = "app"
+	ContainerTypeInit      = "init"
+	ContainerTypeSidecar   = "sidecar"
+	ContainerTypeEphemeral = "ephemeral"
+)
+
+// PodContainer is a container of any type along with its current status
+type PodContainer struct {
+	Name, Type, Image string
+	Resources         v1.ResourceRequirements
+	Status            *v1.ContainerStatus
+}
+
+// Returns true for native sidecars, i.e. init containers with restartPolicy Always
+func IsSidecar(c v1.Container) bool {
+	return c.RestartPolicy != nil && *c.RestartPolicy == v1.ContainerRestartPolicyAlways
+}
+
+func findContainerStatus(statuses []v1.ContainerStatus, name string) *v1.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+/*
+Get all containers of a pod,
+init containers and sidecars first, then app containers and ephemeral containers
+*/
+func GetPodContainers(p v1.Pod) []PodContainer {
+	var containers []PodContainer
+	for _, c := range p.Spec.InitContainers {
+		ctype := ContainerTypeInit
+		if IsSidecar(c) {
+			ctype = ContainerTypeSidecar
+		}
+		containers = append(containers, PodContainer{
+			Name:      c.Name,
+			Type:      ctype,
+			Image:     c.Image,
+			Resources: c.Resources,
+			Status:    findContainerStatus(p.Status.InitContainerStatuses, c.Name),
+		})
+	}
+	for _, c := range p.Spec.Containers {
+		containers = append(containers, PodContainer{
+			Name:      c.Name,
+			Type:      ContainerTypeApp,
+			Image:     c.Image,
+			Resources: c.Resources,
+			Status:    findContainerStatus(p.Status.ContainerStatuses, c.Name),
+		})
+	}
+	for _, c := range p.Spec.EphemeralContainers {
+		containers = append(containers, PodContainer{
+			Name:      c.Name,
+			Type:      ContainerTypeEphemeral,
+			Image:     c.Image,
+			Resources: c.Resources,
+			Status:    findContainerStatus(p.Status.EphemeralContainerStatuses, c.Name),
+		})
+	}
+	return containers
+}
+
+// Get the state of a single container
+func getContainerState(cs *v1.ContainerStatus) string {
+	switch {
+	case cs == nil:
+		return "Unknown"
+	case cs.State.Running != nil:
+		return "Running"
+	case cs.State.Waiting != nil:
+		return cs.State.Waiting.Reason
+	case cs.State.Terminated != nil:
+		if cs.State.Terminated.Reason != "" {
+			return cs.State.Terminated.Reason
+		}
+		return "ExitCode:" + strconv.Itoa(int(cs.State.Terminated.ExitCode))
+	}
+	return "Unknown"
+}
+
+// List every container of the pods with its type
+func ListPodContainers(namespace string) error {
+	pods, err := GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+	w := style.NewWriter()
+	fmt.Fprintln(w, "POD\t\tCONTAINER\t\tTYPE\t\tREADY\t\tSTATE\t\tRESTART\t\tNAMESPACE")
+
+	for _, pod := range pods.Items {
+		for _, c := range GetPodContainers(pod) {
+			ready := "false"
+			var restarts int32
+			if c.Status != nil {
+				ready = strconv.FormatBool(c.Status.Ready)
+				restarts = c.Status.RestartCount
+			}
+			data := pod.Name + "\t\t" + c.Name + "\t\t" + c.Type + "\t\t" + ready + "\t\t" + style.Status(getContainerState(c.Status)) + "\t\t" + strconv.Itoa(int(restarts)) + "\t\t" + pod.Namespace
+			fmt.Fprintln(w, data)
+		}
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/kerrors"
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pdb"
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// Flags of pods which need attention before draining
+const (
+	flagNoController = "NO-CONTROLLER"
+	flagEmptyDir     = "EMPTYDIR"
+	flagLocalStorage = "LOCAL-STORAGE"
+)
+
+// EvictedPod is a pod which would be evicted by draining its node
+type EvictedPod struct {
+	Pod      v1.Pod
+	Workload string
+	Flags    []string
+}
+
+// PDBImpact is how a PodDisruptionBudget reacts to the evictions
+type PDBImpact struct {
+	PDB     policyv1.PodDisruptionBudget
+	Evicted int
+	Blocked bool
+}
+
+// DrainPreview is the impact of draining a set of nodes
+type DrainPreview struct {
+	Nodes             []string
+	Evicted           []EvictedPod
+	SkippedDaemonSets int
+	PDBs              []PDBImpact
+	// requests of the evicted pods and free allocatable on the remaining schedulable nodes
+	DisplacedCPU, DisplacedMem, FreeCPU, FreeMem float64
+	// largest single pod request, it has to fit on one remaining node
+	LargestCPU, LargestMem float64
+	LargestFits            bool
+}
+
+func controllerOf(p v1.Pod) (kind, name string) {
+	for _, o := range p.OwnerReferences {
+		if o.Controller != nil && *o.Controller {
+			return o.Kind, o.Name
+		}
+	}
+	return "", ""
+}
+
+// Get the owning workload of a pod, e.g. Deployment/app-db-live
+func getWorkload(p v1.Pod) string {
+	kind, name := controllerOf(p)
+	switch kind {
+	case "":
+		return "<none>"
+	case "ReplicaSet":
+		// replicasets created by a deployment carry its name and the pod template hash
+		if hash, ok := p.Labels["pod-template-hash"]; ok && strings.HasSuffix(name, "-"+hash) {
+			return "Deployment/" + strings.TrimSuffix(name, "-"+hash)
+		}
+	}
+	return kind + "/" + name
+}
+
+func isMirrorPod(p v1.Pod) bool {
+	_, ok := p.Annotations[v1.MirrorPodAnnotationKey]
+	return ok
+}
+
+/*
+Preview draining nodes,
+daemonset and mirror pods are skipped like kubectl drain does
+*/
+func PreviewDrain(targets []string, nodes []v1.Node, pods []v1.Pod, pdbs []policyv1.PodDisruptionBudget) DrainPreview {
+	draining := make(map[string]bool)
+	for _, t := range targets {
+		draining[t] = true
+	}
+	preview := DrainPreview{Nodes: targets}
+
+	free := make(map[string][2]float64)
+	for _, n := range nodes {
+		if draining[n.Name] || n.Spec.Unschedulable {
+			continue
+		}
+		free[n.Name] = [2]float64{n.Status.Allocatable.Cpu().AsApproximateFloat64(), n.Status.Allocatable.Memory().AsApproximateFloat64()}
+	}
+
+	for _, p := range pods {
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		requests := GetPodRequests(p.Spec)
+		cpu := requests.Cpu().AsApproximateFloat64()
+		mem := requests.Memory().AsApproximateFloat64()
+
+		if f, ok := free[p.Spec.NodeName]; ok {
+			free[p.Spec.NodeName] = [2]float64{f[0] - cpu, f[1] - mem}
+			continue
+		}
+		if !draining[p.Spec.NodeName] || isMirrorPod(p) {
+			continue
+		}
+		if kind, _ := controllerOf(p); kind == "DaemonSet" {
+			preview.SkippedDaemonSets++
+			continue
+		}
+
+		var flags []string
+		if kind, _ := controllerOf(p); kind == "" {
+			flags = append(flags, flagNoController)
+		}
+		for _, vol := range p.Spec.Volumes {
+			if vol.EmptyDir != nil {
+				flags = append(flags, flagEmptyDir)
+				break
+			}
+		}
+		for _, vol := range p.Spec.Volumes {
+			if vol.HostPath != nil {
+				flags = append(flags, flagLocalStorage)
+				break
+			}
+		}
+		preview.Evicted = append(preview.Evicted, EvictedPod{Pod: p, Workload: getWorkload(p), Flags: flags})
+		preview.DisplacedCPU += cpu
+		preview.DisplacedMem += mem
+		if cpu > preview.LargestCPU {
+			preview.LargestCPU = cpu
+		}
+		if mem > preview.LargestMem {
+			preview.LargestMem = mem
+		}
+	}
+
+	for _, f := range free {
+		preview.FreeCPU += f[0]
+		preview.FreeMem += f[1]
+		if f[0] >= preview.LargestCPU && f[1] >= preview.LargestMem {
+			preview.LargestFits = true
+		}
+	}
+	if len(preview.Evicted) == 0 {
+		preview.LargestFits = true
+	}
+
+	sort.SliceStable(preview.Evicted, func(i, j int) bool {
+		if preview.Evicted[i].Workload != preview.Evicted[j].Workload {
+			return preview.Evicted[i].Workload < preview.Evicted[j].Workload
+		}
+		return preview.Evicted[i].Pod.Name < preview.Evicted[j].Pod.Name
+	})
+
+	// a PDB blocks the drain when more of its pods are evicted than it allows to disrupt
+	for _, budget := range pdbs {
+		evicted := 0
+		for _, e := range preview.Evicted {
+			if pdb.Matches(budget, e.Pod.Namespace, e.Pod.Labels) {
+				evicted++
+			}
+		}
+		if evicted == 0 {
+			continue
+		}
+		preview.PDBs = append(preview.PDBs, PDBImpact{
+			PDB:     budget,
+			Evicted: evicted,
+			Blocked: int32(evicted) > budget.Status.DisruptionsAllowed,
+		})
+	}
+	return preview
+}
+
+// Resolve a node name or a node group to node names
+func resolveDrainTargets(target string, nodes []v1.Node) []string {
+	for _, n := range nodes {
+		if n.Name == target {
+			return []string{target}
+		}
+	}
+	var names []string
+	for _, n := range nodes {
+		if node.GetNodeGroup(n) == target {
+			names = append(names, n.Name)
+		}
+	}
+	return names
+}
+
+// Print the pods evicted by draining a node or a node group
+func PrintDrainPreview(target string) error {
+	nodes, err := node.ListNodes()
+	if err != nil {
+		return err
+	}
+	targets := resolveDrainTargets(target, nodes)
+	if len(targets) == 0 {
+		return kerrors.NotFound("node or node group", target)
+	}
+	namespace := ""
+	pods, err := GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+	pdbs, err := pdb.GetPodDisruptionBudgets(&namespace)
+	if err != nil {
+		return err
+	}
+
+	preview := PreviewDrain(targets, nodes, pods.Items, pdbs.Items)
+
+	fmt.Println("Nodes to drain: " + strconv.Itoa(len(preview.Nodes)) + "\t\tPods to evict: " + strconv.Itoa(len(preview.Evicted)) + "\t\tDaemonSet pods skipped: " + strconv.Itoa(preview.SkippedDaemonSets))
+	fmt.Println()
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "WORKLOAD\t\tNAMESPACE\t\tPOD\t\tNODE\t\tSTATUS\t\tFLAGS")
+	for _, e := range preview.Evicted {
+		flags := "-"
+		if len(e.Flags) != 0 {
+			flags = strings.Join(e.Flags, ",")
+		}
+		data := e.Workload + "\t\t" + e.Pod.Namespace + "\t\t" + e.Pod.Name + "\t\t" + e.Pod.Spec.NodeName + "\t\t" + style.Status(GetPodStatus(e.Pod).Reason) + "\t\t" + flags
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+
+	if len(preview.PDBs) != 0 {
+		fmt.Println()
+		w = style.NewWriter()
+		fmt.Fprintln(w, "PDB\t\tNAMESPACE\t\tALLOWED-DISRUPTIONS\t\tEVICTED\t\tRESULT")
+		for _, p := range preview.PDBs {
+			result := "OK"
+			if p.Blocked {
+				result = "BLOCKED"
+			}
+			data := p.PDB.Name + "\t\t" + p.PDB.Namespace + "\t\t" + strconv.Itoa(int(p.PDB.Status.DisruptionsAllowed)) + "\t\t" + strconv.Itoa(p.Evicted) + "\t\t" + result
+			fmt.Fprintln(w, data)
+		}
+		w.Flush()
+	}
+
+	capacity := "OK"
+	if preview.DisplacedCPU > preview.FreeCPU || preview.DisplacedMem > preview.FreeMem || !preview.LargestFits {
+		capacity = "INSUFFICIENT"
+	}
+	fmt.Println()
+	fmt.Println("Displaced Requests: \tCPU: " + fmt.Sprintf("%.2f", preview.DisplacedCPU) + " Cores\t\tMemory: " + strconv.Itoa(int(preview.DisplacedMem/1048576)) + "Mi")
+	fmt.Println("Free on Remaining Nodes: \tCPU: " + fmt.Sprintf("%.2f", preview.FreeCPU) + " Cores\t\tMemory: " + strconv.Itoa(int(preview.FreeMem/1048576)) + "Mi")
+	fmt.Println("Capacity: \t\t" + capacity)
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func drainNode(name string) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.NodeStatus{Allocatable: resourceList("4", "8Gi")},
+	}
+}
+
+func drainPod(name, nodeName, ownerKind, ownerName string, labels map[string]string) v1.Pod {
+	controller := true
+	p := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", Labels: labels},
+		Spec: v1.PodSpec{
+			NodeName:   nodeName,
+			Containers: []v1.Container{container("app", resourceList("1", "1Gi"), nil)},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if ownerKind != "" {
+		p.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+	}
+	return p
+}
+
+func TestPreviewDrain(t *testing.T) {
+	nodes := []v1.Node{drainNode("a"), drainNode("b")}
+	web := map[string]string{"app": "web", "pod-template-hash": "6d4cf56db6"}
+	bare := drainPod("debug", "a", "", "", nil)
+	bare.Spec.Volumes = []v1.Volume{{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	pods := []v1.Pod{
+		drainPod("web-6d4cf56db6-abcde", "a", "ReplicaSet", "web-6d4cf56db6", web),
+		drainPod("web-6d4cf56db6-fghij", "a", "ReplicaSet", "web-6d4cf56db6", web),
+		drainPod("fluentbit-x1", "a", "DaemonSet", "fluentbit", nil),
+		bare,
+		drainPod("web-6d4cf56db6-klmno", "b", "ReplicaSet", "web-6d4cf56db6", web),
+	}
+	pdbs := []policyv1.PodDisruptionBudget{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}}
+
+	preview := PreviewDrain([]string{"a"}, nodes, pods, pdbs)
+	if len(preview.Evicted) != 3 || preview.SkippedDaemonSets != 1 {
+		t.Fatalf("evicted %d skipped %d, want 3 and 1", len(preview.Evicted), preview.SkippedDaemonSets)
+	}
+	if w := preview.Evicted[2].Workload; w != "Deployment/web" {
+		t.Errorf("workload = %q, want Deployment/web", w)
+	}
+	if e := preview.Evicted[0]; e.Workload != "<none>" || len(e.Flags) != 2 || e.Flags[0] != flagNoController || e.Flags[1] != flagEmptyDir {
+		t.Errorf("bare pod = %q %v, want <none> [NO-CONTROLLER EMPTYDIR]", e.Workload, e.Flags)
+	}
+	if len(preview.PDBs) != 1 || preview.PDBs[0].Evicted != 2 || !preview.PDBs[0].Blocked {
+		t.Errorf("pdbs = %+v, want web blocked with 2 evicted", preview.PDBs)
+	}
+	// node b has 3 cores free after its own pod, the drained pods request 3 cores
+	if preview.FreeCPU != 3 || preview.DisplacedCPU != 3 || !preview.LargestFits {
+		t.Errorf("free %v displaced %v, want 3 and 3", preview.FreeCPU, preview.DisplacedCPU)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageUsage is a container image and the pods running it
+type ImageUsage struct {
+	Image, Registry string
+	// distinct digests the image resolved to on the nodes
+	Digests    []string
+	Pods       int
+	Namespaces []string
+	Workloads  []string
+	// creation time of the oldest pod running the image
+	Oldest metav1.Time
+}
+
+// ImageFilter restricts the pods taken into account
+type ImageFilter struct {
+	Registry string
+	// only pods older than this, 0 for all pods
+	OutdatedThan time.Duration
+	Now          time.Time
+}
+
+// Returns true if the same image reference resolved to different digests across pods
+func (u ImageUsage) TagDrift() bool {
+	return len(u.Digests) > 1
+}
+
+// Get the registry of an image, images without one are pulled from docker.io
+func GetRegistry(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return "docker.io"
+	}
+	// the first component is a registry if it looks like a host
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+// Get the digest of a resolved imageID, e.g. docker-pullable://nginx@sha256:... returns sha256:...
+func getDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	if i := strings.Index(imageID, "://"); i >= 0 {
+		return imageID[i+3:]
+	}
+	return imageID
+}
+
+func appendUnique(s []string, v string) []string {
+	for _, e := range s {
+		if e == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
+
+/*
+Aggregate the images of running pods,
+digests come from the container statuses and are only known once a container has started
+*/
+func GetImageUsage(pods []v1.Pod, filter ImageFilter) []ImageUsage {
+	index := make(map[string]int)
+	var usage []ImageUsage
+	for _, p := range pods {
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if filter.OutdatedThan > 0 && filter.Now.Sub(p.CreationTimestamp.Time) < filter.OutdatedThan {
+			continue
+		}
+		counted := make(map[string]bool)
+		for _, c := range GetPodContainers(p) {
+			registry := GetRegistry(c.Image)
+			if filter.Registry != "" && registry != filter.Registry {
+				continue
+			}
+			i, ok := index[c.Image]
+			if !ok {
+				i = len(usage)
+				index[c.Image] = i
+				usage = append(usage, ImageUsage{Image: c.Image, Registry: registry, Oldest: p.CreationTimestamp})
+			}
+			u := &usage[i]
+			if c.Status != nil && c.Status.ImageID != "" {
+				u.Digests = appendUnique(u.Digests, getDigest(c.Status.ImageID))
+			}
+			// a pod running the image in several containers counts once
+			if counted[c.Image] {
+				continue
+			}
+			counted[c.Image] = true
+			u.Pods++
+			u.Namespaces = appendUnique(u.Namespaces, p.Namespace)
+			u.Workloads = appendUnique(u.Workloads, p.Namespace+"/"+getWorkload(p))
+			if p.CreationTimestamp.Before(&u.Oldest) {
+				u.Oldest = p.CreationTimestamp
+			}
+		}
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		return usage[i].Image < usage[j].Image
+	})
+	return usage
+}
+
+// Print the images running in the cluster with the pods, namespaces and workloads using them
+func ListImages(namespace, registry string, outdatedThan time.Duration) error {
+	pods, err := GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+	usage := GetImageUsage(pods.Items, ImageFilter{Registry: registry, OutdatedThan: outdatedThan, Now: time.Now()})
+
+	w := style.NewWriter()
+	fmt.Fprintln(w, "IMAGE\t\tREGISTRY\t\tPODS\t\tNAMESPACES\t\tWORKLOADS\t\tDIGESTS\t\tOLDEST-POD\t\tFLAG")
+	for _, u := range usage {
+		flag := "-"
+		if u.TagDrift() {
+			flag = "TAG-DRIFT"
+		}
+		data := u.Image + "\t\t" + u.Registry + "\t\t" + strconv.Itoa(u.Pods) + "\t\t" + strconv.Itoa(len(u.Namespaces)) + "\t\t" + strconv.Itoa(len(u.Workloads)) + "\t\t" + strconv.Itoa(len(u.Digests)) + "\t\t" + FormatAge(u.Oldest.Time) + "\t\t" + flag
+		fmt.Fprintln(w, data)
+	}
+	w.Flush()
+
+	// digests of drifted tags, so the pods can be traced
+	for _, u := range usage {
+		if !u.TagDrift() {
+			continue
+		}
+		fmt.Println("\n" + u.Image + " resolves to:")
+		for _, d := range u.Digests {
+			fmt.Println("  " + d)
+		}
+	}
+	return nil
+}
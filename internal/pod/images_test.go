@@ -0,0 +1,113 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetRegistry(t *testing.T) {
+	tests := []struct {
+		image, want string
+	}{
+		{"nginx:1.25", "docker.io"},
+		{"bitnami/redis:7.2", "docker.io"},
+		{"quay.io/prometheus/node-exporter:v1.6.1", "quay.io"},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com/app:1.0", "123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+		{"localhost:5000/app", "localhost:5000"},
+		{"localhost/app", "localhost"},
+	}
+	for _, tt := range tests {
+		if got := GetRegistry(tt.image); got != tt.want {
+			t.Errorf("GetRegistry(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestGetDigest(t *testing.T) {
+	tests := []struct {
+		imageID, want string
+	}{
+		{"docker-pullable://nginx@sha256:abc", "sha256:abc"},
+		{"docker.io/library/nginx@sha256:abc", "sha256:abc"},
+		{"sha256:abc", "sha256:abc"},
+		{"docker://sha256:abc", "sha256:abc"},
+	}
+	for _, tt := range tests {
+		if got := getDigest(tt.imageID); got != tt.want {
+			t.Errorf("getDigest(%q) = %q, want %q", tt.imageID, got, tt.want)
+		}
+	}
+}
+
+func imagePod(namespace, owner, image, imageID string, created time.Time) v1.Pod {
+	controller := true
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "StatefulSet", Name: owner, Controller: &controller}},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: image}}},
+		Status: v1.PodStatus{
+			Phase:             v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "app", ImageID: imageID}},
+		},
+	}
+}
+
+func TestGetImageUsage(t *testing.T) {
+	now := time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC)
+	pods := []v1.Pod{
+		imagePod("app", "web", "nginx:1.25", "docker.io/library/nginx@sha256:aaa", now.Add(-48*time.Hour)),
+		imagePod("app", "web", "nginx:1.25", "docker.io/library/nginx@sha256:bbb", now.Add(-time.Hour)),
+		imagePod("edge", "proxy", "nginx:1.25", "docker.io/library/nginx@sha256:aaa", now.Add(-time.Hour)),
+		imagePod("app", "api", "quay.io/org/api:2.0", "quay.io/org/api@sha256:ccc", now.Add(-72*time.Hour)),
+	}
+	completed := imagePod("app", "job", "busybox", "", now)
+	completed.Status.Phase = v1.PodSucceeded
+	pods = append(pods, completed)
+
+	usage := GetImageUsage(pods, ImageFilter{Now: now})
+	if len(usage) != 2 {
+		t.Fatalf("GetImageUsage() returned %d images, want 2", len(usage))
+	}
+	nginx := usage[0]
+	if nginx.Image != "nginx:1.25" || nginx.Pods != 3 || len(nginx.Namespaces) != 2 || len(nginx.Workloads) != 2 || !nginx.TagDrift() {
+		t.Errorf("nginx usage = %+v", nginx)
+	}
+	if !nginx.Oldest.Time.Equal(now.Add(-48 * time.Hour)) {
+		t.Errorf("nginx oldest = %v, want %v", nginx.Oldest.Time, now.Add(-48*time.Hour))
+	}
+	if usage[1].TagDrift() {
+		t.Errorf("api usage has tag drift, want none")
+	}
+
+	filtered := GetImageUsage(pods, ImageFilter{Registry: "quay.io", Now: now})
+	if len(filtered) != 1 || filtered[0].Image != "quay.io/org/api:2.0" {
+		t.Errorf("registry filter = %+v, want only quay.io/org/api:2.0", filtered)
+	}
+
+	outdated := GetImageUsage(pods, ImageFilter{OutdatedThan: 24 * time.Hour, Now: now})
+	if len(outdated) != 2 || outdated[0].Pods != 1 || outdated[0].TagDrift() {
+		t.Errorf("outdated filter = %+v, want one nginx pod without drift", outdated)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+/*
+PodIndex looks up pods by node, owning workload and name,
+build it once per command instead of scanning the pod list for every object
+*/
+type PodIndex struct {
+	byNode     map[string][]*v1.Pod
+	byWorkload map[string][]*v1.Pod
+	byName     map[string]*v1.Pod
+}
+
+// Index a list of pods, the index points into the list so it must not be modified
+func NewPodIndex(pods []v1.Pod) *PodIndex {
+	index := &PodIndex{
+		byNode:     make(map[string][]*v1.Pod),
+		byWorkload: make(map[string][]*v1.Pod),
+		byName:     make(map[string]*v1.Pod, len(pods)),
+	}
+	for i := range pods {
+		p := &pods[i]
+		if p.Spec.NodeName != "" {
+			index.byNode[p.Spec.NodeName] = append(index.byNode[p.Spec.NodeName], p)
+		}
+		workload := p.Namespace + "/" + getWorkload(*p)
+		index.byWorkload[workload] = append(index.byWorkload[workload], p)
+		index.byName[p.Namespace+"/"+p.Name] = p
+	}
+	return index
+}
+
+// Get the pods scheduled on a node
+func (i *PodIndex) OnNode(nodeName string) []*v1.Pod {
+	return i.byNode[nodeName]
+}
+
+// Get the pods of a workload, e.g. Deployment/app-db-live
+func (i *PodIndex) ForWorkload(namespace, workload string) []*v1.Pod {
+	return i.byWorkload[namespace+"/"+workload]
+}
+
+// Get a pod by namespace and name, nil if it does not exist
+func (i *PodIndex) Get(namespace, name string) *v1.Pod {
+	return i.byName[namespace+"/"+name]
+}
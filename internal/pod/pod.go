@@ -17,26 +17,15 @@ limitations under the License.
 package pod
 
 import (
-	"context"
-	"fmt"
-	"os"
 	"strconv"
-	"text/tabwriter"
 	"time"
 
-	k8sclient "github.com/sam0392in/kshow/internal/client"
-	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/cache"
 
-	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
-var (
-	logger *zap.Logger
-)
-
 type ContainerDetails struct {
 	Name, Currentcpu, Currentmemory string
 }
@@ -46,33 +35,31 @@ type PodResourceStats struct {
 	Containerstats  []ContainerDetails
 }
 
-func init() {
-	logger, _ = zap.NewProduction()
-
-}
-
-func client(namespace *string) (*kubernetes.Clientset, error) {
-	clientset, err := k8sclient.GetK8sClient()
+func GetPods(namespace *string) (*v1.PodList, error) {
+	source, err := cache.GetSource()
 	if err != nil {
-		logger.Error(err.Error())
+		return nil, err
 	}
-	return clientset, err
-}
-
-func GetPods(namespace *string) (*v1.PodList, error) {
-	clientset, err := client(namespace)
+	items, err := source.Pods(*namespace)
 	if err != nil {
-		logger.Error(err.Error())
+		return nil, err
 	}
-	podClient := clientset.CoreV1().Pods(*namespace)
-	list, err := podClient.List(context.TODO(), metav1.ListOptions{})
-	return list, err
+	return &v1.PodList{Items: items}, nil
 }
 
-// Get the Age of pod
-func getPodAge(podCreationTime metav1.Time) string {
+/*
+List pods in chunks of k8sclient.ListChunkSize,
+keeps each response small on clusters with thousands of pods
+*/
+func ListPodsChunked(clientset kubernetes.Interface, namespace string) (*v1.PodList, error) {
+	items, err := cache.NewDirect(clientset).Pods(namespace)
+	return &v1.PodList{Items: items}, err
+}
+
+// Get the age of an object created at the given time, e.g. 5d
+func FormatAge(created time.Time) string {
 	var ageS string
-	age := time.Since(podCreationTime.Time).Round(time.Second)
+	age := time.Since(created).Round(time.Second)
 	ageS = age.String()
 	if age.Hours() > 8760 {
 		ageInYears := int((age.Hours() + (age.Minutes() / 60)) / 8760)
@@ -89,104 +76,3 @@ func getPodAge(podCreationTime metav1.Time) string {
 	}
 	return ageS
 }
-
-// Get Pod status
-func getPodStatus(podStatus v1.PodStatus) string {
-	var status string
-	if podStatus.Phase != "Running" && podStatus.Phase != "Succeeded" && len(podStatus.ContainerStatuses) != 0 {
-		for _, cs := range podStatus.ContainerStatuses {
-			if !(cs.Ready) && (cs.State.Waiting != nil) {
-				status = cs.State.Waiting.Reason
-				break
-			} else {
-				status = string(podStatus.Phase)
-			}
-		}
-	} else {
-		status = string(podStatus.Phase)
-	}
-	return status
-}
-
-// List pods
-func ListPods(namespace string) {
-	pods, err := GetPods(&namespace)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "POD\t\tREADY\t\tSTATUS\t\tRESTART\t\tAGE\t\tNAMESPACE")
-
-	for _, pod := range pods.Items {
-
-		// Calculate the age of the pod
-		var ageS string
-		podCreationTime := pod.GetCreationTimestamp()
-		ageS = getPodAge(podCreationTime)
-
-		// Get the status of each of the pods
-		podStatus := pod.Status
-		var status string
-		status = getPodStatus(podStatus)
-
-		var containerRestarts int32
-		var containerReady int
-		var totalContainers int
-
-		// If a pod has multiple containers, get the status from all
-		for _, s := range pod.Status.ContainerStatuses {
-			containerRestarts += s.RestartCount
-			if s.Ready {
-				containerReady++
-			}
-			totalContainers++
-		}
-
-		// Get the values from the pod status
-		name := pod.Name
-		namespace := pod.Namespace
-		ready := fmt.Sprintf("%v/%v", containerReady, totalContainers)
-		// status := fmt.Sprintf("%v", podStatus.)
-		restarts := fmt.Sprintf("%v", containerRestarts)
-
-		data := name + "\t\t" + ready + "\t\t" + status + "\t\t" + restarts + "\t\t" + ageS + "\t\t" + namespace
-		fmt.Fprintln(w, data)
-	}
-	w.Flush()
-}
-
-// List Pods with node tenancy (only for AWS EKS)
-func ListPodswithNodeTenency(namespace string) {
-	pods, err := GetPods(&namespace)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	// get all nodes in the cluster
-	nodes, err := node.ListNodes()
-
-	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	fmt.Fprintln(w, "POD\t\tAGE\t\tSTATUS\t\tNAMESPACE\t\tNODE\t\tTENANCY")
-	for _, pod := range pods.Items {
-		for _, node := range nodes {
-			podNode := pod.Spec.NodeName
-			nodeName := node.ObjectMeta.Labels["kubernetes.io/hostname"]
-			nodeTenancy := node.ObjectMeta.Labels["eks.amazonaws.com/capacityType"]
-			if podNode == nodeName {
-
-				// Calculate the age of the pod
-				var ageS string
-				podCreationTime := pod.GetCreationTimestamp()
-				ageS = getPodAge(podCreationTime)
-
-				// Get the status of each of the pods
-				podStatus := pod.Status
-				var status string
-				status = getPodStatus(podStatus)
-
-				data := pod.Name + "\t\t" + ageS + "\t\t" + status + "\t\t" + pod.Namespace + "\t\t" + podNode + "\t\t" + nodeTenancy
-				fmt.Fprintln(w, data)
-			}
-		}
-	}
-	w.Flush()
-}
@@ -0,0 +1,210 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/style"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Requests and limits of a single container
+type ContainerResources struct {
+	RequestCPU, LimitCPU, RequestMem, LimitMem resource.Quantity
+}
+
+// Get the cpu and memory requests and limits of a container
+func GetContainerResources(r v1.ResourceRequirements) ContainerResources {
+	return ContainerResources{
+		RequestCPU: *r.Requests.Cpu(),
+		LimitCPU:   *r.Limits.Cpu(),
+		RequestMem: *r.Requests.Memory(),
+		LimitMem:   *r.Limits.Memory(),
+	}
+}
+
+// Returns the requests and limits which are not set, e.g. [cpu-limit mem-request]
+func (r ContainerResources) Missing() []string {
+	var missing []string
+	if r.RequestCPU.IsZero() {
+		missing = append(missing, "cpu-request")
+	}
+	if r.LimitCPU.IsZero() {
+		missing = append(missing, "cpu-limit")
+	}
+	if r.RequestMem.IsZero() {
+		missing = append(missing, "mem-request")
+	}
+	if r.LimitMem.IsZero() {
+		missing = append(missing, "mem-limit")
+	}
+	return missing
+}
+
+// Request to limit ratio, e.g. 1:2.0, "-" when either is not set
+func ratio(request, limit resource.Quantity) string {
+	if request.IsZero() || limit.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("1:%.1f", limit.AsApproximateFloat64()/request.AsApproximateFloat64())
+}
+
+func (r ContainerResources) CPURatio() string {
+	return ratio(r.RequestCPU, r.LimitCPU)
+}
+
+func (r ContainerResources) MemRatio() string {
+	return ratio(r.RequestMem, r.LimitMem)
+}
+
+/*
+Get QoS class of a pod spec,
+follows the kubelet rules. A container with a limit but no request is
+treated as requesting its limit, as the API server defaults it that way
+*/
+func GetQOSClass(spec v1.PodSpec) v1.PodQOSClass {
+	requests := v1.ResourceList{}
+	limits := v1.ResourceList{}
+	guaranteed := true
+
+	all := append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range all {
+		limitsFound := 0
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			req, hasReq := c.Resources.Requests[name]
+			lim, hasLim := c.Resources.Limits[name]
+			if (!hasReq || req.IsZero()) && hasLim && !lim.IsZero() {
+				req, hasReq = lim, true
+			}
+			if hasReq && !req.IsZero() {
+				total := requests[name]
+				total.Add(req)
+				requests[name] = total
+			}
+			if hasLim && !lim.IsZero() {
+				limitsFound++
+				total := limits[name]
+				total.Add(lim)
+				limits[name] = total
+			}
+		}
+		if limitsFound != 2 {
+			guaranteed = false
+		}
+	}
+
+	if len(requests) == 0 && len(limits) == 0 {
+		return v1.PodQOSBestEffort
+	}
+	if guaranteed {
+		for name, req := range requests {
+			if lim, ok := limits[name]; !ok || lim.Cmp(req) != 0 {
+				guaranteed = false
+				break
+			}
+		}
+	}
+	if guaranteed && len(requests) == len(limits) {
+		return v1.PodQOSGuaranteed
+	}
+	return v1.PodQOSBurstable
+}
+
+// Get QoS class of a pod, as reported by the API server when available
+func getPodQOSClass(p v1.Pod) v1.PodQOSClass {
+	if p.Status.QOSClass != "" {
+		return p.Status.QOSClass
+	}
+	return GetQOSClass(p.Spec)
+}
+
+// List requests, limits and QoS class of every container of the pods
+func ListPodResources(namespace string) error {
+	pods, err := GetPods(&namespace)
+	if err != nil {
+		return err
+	}
+	w := style.NewWriter()
+	fmt.Fprintln(w, "POD\t\tCONTAINER\t\tTYPE\t\tQOS\t\tREQ-CPU\t\tLIMIT-CPU\t\tCPU-RATIO\t\tREQ-MEM\t\tLIMIT-MEM\t\tMEM-RATIO\t\tMISSING\t\tNAMESPACE")
+
+	for _, pod := range pods.Items {
+		qos := string(getPodQOSClass(pod))
+		for _, c := range GetPodContainers(pod) {
+			// ephemeral containers can not have resources
+			if c.Type == ContainerTypeEphemeral {
+				continue
+			}
+			r := GetContainerResources(c.Resources)
+			missing := "-"
+			if m := r.Missing(); len(m) != 0 {
+				missing = strings.Join(m, ",")
+			}
+			data := pod.Name + "\t\t" + c.Name + "\t\t" + c.Type + "\t\t" + qos + "\t\t" + r.RequestCPU.String() + "\t\t" + r.LimitCPU.String() + "\t\t" + r.CPURatio() + "\t\t" + r.RequestMem.String() + "\t\t" + r.LimitMem.String() + "\t\t" + r.MemRatio() + "\t\t" + missing + "\t\t" + pod.Namespace
+			fmt.Fprintln(w, data)
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+/*
+Get the effective cpu and memory requests of a pod spec,
+the larger of app containers plus sidecars and any init container plus the sidecars started before it
+*/
+func GetPodRequests(spec v1.PodSpec) v1.ResourceList {
+	total := v1.ResourceList{}
+	sidecars := v1.ResourceList{}
+	for _, c := range spec.InitContainers {
+		if IsSidecar(c) {
+			addResources(sidecars, c.Resources.Requests)
+			continue
+		}
+		initRequests := v1.ResourceList{}
+		addResources(initRequests, sidecars)
+		addResources(initRequests, c.Resources.Requests)
+		maxResources(total, initRequests)
+	}
+	running := v1.ResourceList{}
+	addResources(running, sidecars)
+	for _, c := range spec.Containers {
+		addResources(running, c.Resources.Requests)
+	}
+	maxResources(total, running)
+	return total
+}
+
+func addResources(total, l v1.ResourceList) {
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		if q, ok := l[name]; ok {
+			t := total[name]
+			t.Add(q)
+			total[name] = t
+		}
+	}
+}
+
+func maxResources(total, l v1.ResourceList) {
+	for name, q := range l {
+		if t, ok := total[name]; !ok || q.Cmp(t) > 0 {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func resourceList(cpu, mem string) v1.ResourceList {
+	l := v1.ResourceList{}
+	if cpu != "" {
+		l[v1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if mem != "" {
+		l[v1.ResourceMemory] = resource.MustParse(mem)
+	}
+	return l
+}
+
+func container(name string, requests, limits v1.ResourceList) v1.Container {
+	return v1.Container{Name: name, Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}
+}
+
+func TestGetQOSClass(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1.PodSpec
+		want v1.PodQOSClass
+	}{
+		{
+			name: "no resources",
+			spec: v1.PodSpec{Containers: []v1.Container{container("app", nil, nil)}},
+			want: v1.PodQOSBestEffort,
+		},
+		{
+			name: "requests equal limits",
+			spec: v1.PodSpec{Containers: []v1.Container{container("app", resourceList("500m", "1Gi"), resourceList("500m", "1Gi"))}},
+			want: v1.PodQOSGuaranteed,
+		},
+		{
+			name: "limits only",
+			spec: v1.PodSpec{Containers: []v1.Container{container("app", nil, resourceList("1", "1Gi"))}},
+			want: v1.PodQOSGuaranteed,
+		},
+		{
+			name: "requests lower than limits",
+			spec: v1.PodSpec{Containers: []v1.Container{container("app", resourceList("100m", "1Gi"), resourceList("500m", "1Gi"))}},
+			want: v1.PodQOSBurstable,
+		},
+		{
+			name: "memory limit missing",
+			spec: v1.PodSpec{Containers: []v1.Container{container("app", resourceList("500m", "1Gi"), resourceList("500m", ""))}},
+			want: v1.PodQOSBurstable,
+		},
+		{
+			name: "one container without resources",
+			spec: v1.PodSpec{Containers: []v1.Container{
+				container("app", resourceList("500m", "1Gi"), resourceList("500m", "1Gi")),
+				container("proxy", nil, nil),
+			}},
+			want: v1.PodQOSBurstable,
+		},
+		{
+			name: "init container without limits",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{container("migrate", resourceList("100m", ""), nil)},
+				Containers:     []v1.Container{container("app", resourceList("500m", "1Gi"), resourceList("500m", "1Gi"))},
+			},
+			want: v1.PodQOSBurstable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetQOSClass(tt.spec); got != tt.want {
+				t.Errorf("GetQOSClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerResources(t *testing.T) {
+	r := GetContainerResources(v1.ResourceRequirements{
+		Requests: resourceList("250m", "512Mi"),
+		Limits:   resourceList("1", ""),
+	})
+	if got, want := r.Missing(), []string{"mem-limit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %v, want %v", got, want)
+	}
+	if got, want := r.CPURatio(), "1:4.0"; got != want {
+		t.Errorf("CPURatio() = %v, want %v", got, want)
+	}
+	if got, want := r.MemRatio(), "-"; got != want {
+		t.Errorf("MemRatio() = %v, want %v", got, want)
+	}
+}
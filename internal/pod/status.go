@@ -0,0 +1,168 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// reference:
+/*
+	kubectl pod printer:
+	https://github.com/kubernetes/kubernetes/blob/v1.28.4/pkg/printers/internalversion/printers.go
+*/
+
+package pod
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Reason set by the node lifecycle controller on pods of unreachable nodes
+const nodeUnreachablePodReason = "NodeLost"
+
+// PodStatus is the READY, STATUS and RESTART columns of a pod as kubectl prints them
+type PodStatus struct {
+	Reason                 string
+	Ready, Total, Restarts int
+}
+
+func hasPodReadyCondition(conditions []v1.PodCondition) bool {
+	for _, c := range conditions {
+		if c.Type == v1.PodReady && c.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func isPodInitializedConditionTrue(status v1.PodStatus) bool {
+	for _, c := range status.Conditions {
+		if c.Type == v1.PodInitialized {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+/*
+Derive the status of a pod,
+follows the kubectl printer so STATUS matches `kubectl get pods`
+*/
+func GetPodStatus(pod v1.Pod) PodStatus {
+	var (
+		restarts, sidecarRestarts, ready int
+	)
+	total := len(pod.Spec.Containers)
+
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Reason == v1.PodReasonSchedulingGated {
+			reason = v1.PodReasonSchedulingGated
+		}
+	}
+
+	initContainers := make(map[string]v1.Container)
+	for _, c := range pod.Spec.InitContainers {
+		initContainers[c.Name] = c
+		if IsSidecar(c) {
+			total++
+		}
+	}
+
+	initializing := false
+	for i, cs := range pod.Status.InitContainerStatuses {
+		restarts += int(cs.RestartCount)
+		sidecar := IsSidecar(initContainers[cs.Name])
+		if sidecar {
+			sidecarRestarts += int(cs.RestartCount)
+		}
+		switch {
+		case cs.State.Terminated != nil && cs.State.Terminated.ExitCode == 0:
+			continue
+		case sidecar && cs.Started != nil && *cs.Started:
+			if cs.Ready {
+				ready++
+			}
+			continue
+		case cs.State.Terminated != nil:
+			// initialization has failed
+			if cs.State.Terminated.Reason == "" {
+				if cs.State.Terminated.Signal != 0 {
+					reason = "Init:Signal:" + strconv.Itoa(int(cs.State.Terminated.Signal))
+				} else {
+					reason = "Init:ExitCode:" + strconv.Itoa(int(cs.State.Terminated.ExitCode))
+				}
+			} else {
+				reason = "Init:" + cs.State.Terminated.Reason
+			}
+			initializing = true
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "" && cs.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + cs.State.Waiting.Reason
+			initializing = true
+		default:
+			reason = "Init:" + strconv.Itoa(i) + "/" + strconv.Itoa(len(pod.Spec.InitContainers))
+			initializing = true
+		}
+		break
+	}
+
+	if !initializing || isPodInitializedConditionTrue(pod.Status) {
+		restarts = sidecarRestarts
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			cs := pod.Status.ContainerStatuses[i]
+			restarts += int(cs.RestartCount)
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+				reason = cs.State.Waiting.Reason
+			} else if cs.State.Terminated != nil && cs.State.Terminated.Reason != "" {
+				reason = cs.State.Terminated.Reason
+			} else if cs.State.Terminated != nil {
+				if cs.State.Terminated.Signal != 0 {
+					reason = "Signal:" + strconv.Itoa(int(cs.State.Terminated.Signal))
+				} else {
+					reason = "ExitCode:" + strconv.Itoa(int(cs.State.Terminated.ExitCode))
+				}
+			} else if cs.Ready && cs.State.Running != nil {
+				hasRunning = true
+				ready++
+			}
+		}
+
+		// a pod with at least one running container is still Running
+		if reason == "Completed" && hasRunning {
+			if hasPodReadyCondition(pod.Status.Conditions) {
+				reason = "Running"
+			} else {
+				reason = "NotReady"
+			}
+		}
+	}
+
+	if pod.DeletionTimestamp != nil && pod.Status.Reason == nodeUnreachablePodReason {
+		reason = "Unknown"
+	} else if pod.DeletionTimestamp != nil {
+		reason = "Terminating"
+	}
+
+	return PodStatus{
+		Reason:   reason,
+		Ready:    ready,
+		Total:    total,
+		Restarts: restarts,
+	}
+}
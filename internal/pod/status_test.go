@@ -0,0 +1,319 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	always  = v1.ContainerRestartPolicyAlways
+	started = true
+)
+
+func running(name string, ready bool) v1.ContainerStatus {
+	return v1.ContainerStatus{Name: name, Ready: ready, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}
+}
+
+func waiting(name, reason string) v1.ContainerStatus {
+	return v1.ContainerStatus{Name: name, State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason}}}
+}
+
+func terminated(name, reason string, exitCode, signal int32) v1.ContainerStatus {
+	return v1.ContainerStatus{Name: name, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode, Signal: signal}}}
+}
+
+func containers(names ...string) []v1.Container {
+	var cs []v1.Container
+	for _, n := range names {
+		cs = append(cs, v1.Container{Name: n})
+	}
+	return cs
+}
+
+func TestGetPodStatus(t *testing.T) {
+	now := metav1.Now()
+
+	tests := []struct {
+		name string
+		pod  v1.Pod
+		want PodStatus
+	}{
+		{
+			name: "running",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{running("app", true)}},
+			},
+			want: PodStatus{Reason: "Running", Ready: 1, Total: 1},
+		},
+		{
+			name: "pending without container statuses",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodPending},
+			},
+			want: PodStatus{Reason: "Pending", Total: 1},
+		},
+		{
+			name: "container creating",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodPending, ContainerStatuses: []v1.ContainerStatus{waiting("app", "ContainerCreating")}},
+			},
+			want: PodStatus{Reason: "ContainerCreating", Total: 1},
+		},
+		{
+			name: "container creating after readiness loss in running phase",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{Containers: containers("app", "proxy")},
+				Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{
+					running("app", true),
+					waiting("proxy", "ContainerCreating"),
+				}},
+			},
+			want: PodStatus{Reason: "ContainerCreating", Ready: 1, Total: 2},
+		},
+		{
+			name: "crash loop in running phase",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{
+					func() v1.ContainerStatus {
+						cs := waiting("app", "CrashLoopBackOff")
+						cs.RestartCount = 7
+						return cs
+					}(),
+				}},
+			},
+			want: PodStatus{Reason: "CrashLoopBackOff", Total: 1, Restarts: 7},
+		},
+		{
+			name: "completed",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("job")},
+				Status: v1.PodStatus{Phase: v1.PodSucceeded, ContainerStatuses: []v1.ContainerStatus{terminated("job", "Completed", 0, 0)}},
+			},
+			want: PodStatus{Reason: "Completed", Total: 1},
+		},
+		{
+			name: "completed container next to a running one and pod ready",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{Containers: containers("app", "job")},
+				Status: v1.PodStatus{
+					Phase:      v1.PodRunning,
+					Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+					ContainerStatuses: []v1.ContainerStatus{
+						terminated("job", "Completed", 0, 0),
+						running("app", true),
+					},
+				},
+			},
+			want: PodStatus{Reason: "Running", Ready: 1, Total: 2},
+		},
+		{
+			name: "completed container next to a running one and pod not ready",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{Containers: containers("app", "job")},
+				Status: v1.PodStatus{
+					Phase: v1.PodRunning,
+					ContainerStatuses: []v1.ContainerStatus{
+						terminated("job", "Completed", 0, 0),
+						running("app", true),
+					},
+				},
+			},
+			want: PodStatus{Reason: "NotReady", Ready: 1, Total: 2},
+		},
+		{
+			name: "terminated with exit code and no reason",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodFailed, ContainerStatuses: []v1.ContainerStatus{terminated("app", "", 137, 0)}},
+			},
+			want: PodStatus{Reason: "ExitCode:137", Total: 1},
+		},
+		{
+			name: "terminated by signal",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodFailed, ContainerStatuses: []v1.ContainerStatus{terminated("app", "", 0, 9)}},
+			},
+			want: PodStatus{Reason: "Signal:9", Total: 1},
+		},
+		{
+			name: "evicted",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{Phase: v1.PodFailed, Reason: "Evicted"},
+			},
+			want: PodStatus{Reason: "Evicted", Total: 1},
+		},
+		{
+			name: "terminating",
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Spec:       v1.PodSpec{Containers: containers("app")},
+				Status:     v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{running("app", true)}},
+			},
+			want: PodStatus{Reason: "Terminating", Ready: 1, Total: 1},
+		},
+		{
+			name: "node lost",
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Spec:       v1.PodSpec{Containers: containers("app")},
+				Status:     v1.PodStatus{Phase: v1.PodRunning, Reason: "NodeLost", ContainerStatuses: []v1.ContainerStatus{running("app", true)}},
+			},
+			want: PodStatus{Reason: "Unknown", Ready: 1, Total: 1},
+		},
+		{
+			name: "scheduling gated",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase:      v1.PodPending,
+					Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Reason: v1.PodReasonSchedulingGated}},
+				},
+			},
+			want: PodStatus{Reason: "SchedulingGated", Total: 1},
+		},
+		{
+			name: "init progress",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{InitContainers: containers("a", "b", "c"), Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase: v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{
+						terminated("a", "Completed", 0, 0),
+						running("b", false),
+						waiting("c", "PodInitializing"),
+					},
+					ContainerStatuses: []v1.ContainerStatus{waiting("app", "PodInitializing")},
+				},
+			},
+			want: PodStatus{Reason: "Init:1/3", Total: 1},
+		},
+		{
+			name: "init crash loop",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase: v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{
+						func() v1.ContainerStatus {
+							cs := waiting("migrate", "CrashLoopBackOff")
+							cs.RestartCount = 3
+							return cs
+						}(),
+					},
+					ContainerStatuses: []v1.ContainerStatus{waiting("app", "PodInitializing")},
+				},
+			},
+			want: PodStatus{Reason: "Init:CrashLoopBackOff", Total: 1, Restarts: 3},
+		},
+		{
+			name: "init failed with exit code",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase:                 v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{terminated("migrate", "", 2, 0)},
+				},
+			},
+			want: PodStatus{Reason: "Init:ExitCode:2", Total: 1},
+		},
+		{
+			name: "init failed with reason",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase:                 v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{terminated("migrate", "Error", 1, 0)},
+				},
+			},
+			want: PodStatus{Reason: "Init:Error", Total: 1},
+		},
+		{
+			name: "started sidecar counts towards ready",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{
+					InitContainers: []v1.Container{{Name: "proxy", RestartPolicy: &always}},
+					Containers:     containers("app"),
+				},
+				Status: v1.PodStatus{
+					Phase: v1.PodRunning,
+					InitContainerStatuses: []v1.ContainerStatus{
+						func() v1.ContainerStatus {
+							cs := running("proxy", true)
+							cs.Started = &started
+							cs.RestartCount = 2
+							return cs
+						}(),
+					},
+					ContainerStatuses: []v1.ContainerStatus{running("app", true)},
+				},
+			},
+			want: PodStatus{Reason: "Running", Ready: 2, Total: 2, Restarts: 2},
+		},
+		{
+			name: "sidecar not started yet",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{
+					InitContainers: []v1.Container{{Name: "proxy", RestartPolicy: &always}},
+					Containers:     containers("app"),
+				},
+				Status: v1.PodStatus{
+					Phase:                 v1.PodPending,
+					InitContainerStatuses: []v1.ContainerStatus{waiting("proxy", "ContainerCreating")},
+					ContainerStatuses:     []v1.ContainerStatus{waiting("app", "PodInitializing")},
+				},
+			},
+			want: PodStatus{Reason: "Init:ContainerCreating", Total: 2},
+		},
+		{
+			name: "restarts of completed init containers are dropped once initialized",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: v1.PodStatus{
+					Phase: v1.PodRunning,
+					InitContainerStatuses: []v1.ContainerStatus{
+						func() v1.ContainerStatus {
+							cs := terminated("migrate", "Completed", 0, 0)
+							cs.RestartCount = 4
+							return cs
+						}(),
+					},
+					ContainerStatuses: []v1.ContainerStatus{running("app", true)},
+				},
+			},
+			want: PodStatus{Reason: "Running", Ready: 1, Total: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetPodStatus(tt.pod)
+			if got != tt.want {
+				t.Errorf("GetPodStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
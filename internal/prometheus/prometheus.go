@@ -0,0 +1,310 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package prometheus reads pod and node usage from the cAdvisor series of a
+Prometheus HTTP API, as a metrics source in place of metrics-server:
+
+	container_cpu_usage_seconds_total
+	container_memory_working_set_bytes
+	container_cpu_cfs_periods_total
+	container_cpu_cfs_throttled_periods_total
+
+Usage is the same working set memory and cpu rate metrics-server reports,
+with history the averages and peaks over a window are read too
+*/
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/metrics"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Range of the cpu rate of the current usage, long enough for a few scrapes at the usual 30s-1m intervals
+const rateRange = 5 * time.Minute
+
+// Step of the subquery the cpu peak is taken from
+const peakStep = time.Minute
+
+// Series of the root cgroup of a node, its usage is the usage of the node
+const nodeSelector = `id="/"`
+
+// Client of the Prometheus HTTP API, a metrics.WindowSource
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+var _ metrics.WindowSource = &Client{}
+
+// Create a client of the Prometheus at url, e.g. http://prometheus.monitoring:9090
+func New(url string) *Client {
+	return &Client{url: strings.TrimSuffix(url, "/"), http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// A series of an instant vector
+type series struct {
+	labels map[string]string
+	value  float64
+	at     time.Time
+}
+
+// Response of /api/v1/query
+type response struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]interface{}    `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Run an instant query, its result must be a vector
+func (c *Client) query(ctx context.Context, promql string) ([]series, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/api/v1/query?query="+url.QueryEscape(promql), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus %s: %w", c.url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("prometheus %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("prometheus query %s: %s: %s", promql, r.ErrorType, r.Error)
+	}
+	if r.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("prometheus query %s returned a %s, not a vector", promql, r.Data.ResultType)
+	}
+
+	out := make([]series, 0, len(r.Data.Result))
+	for _, s := range r.Data.Result {
+		at, _ := s.Value[0].(float64)
+		text, _ := s.Value[1].(string)
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query %s: value %q: %w", promql, text, err)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		sec, frac := math.Modf(at)
+		out = append(out, series{labels: s.Metric, value: value, at: time.Unix(int64(sec), int64(frac*1e9))})
+	}
+	return out, nil
+}
+
+// Format a duration for PromQL in whole seconds
+func promDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d.Seconds()), 10) + "s"
+}
+
+// Selector of the containers of a namespace, all namespaces if empty. The pause container and pod cgroups are left out
+func containerSelector(namespace string) string {
+	sel := `container!="",container!="POD"`
+	if namespace != "" {
+		sel += `,namespace="` + namespace + `"`
+	}
+	return sel
+}
+
+// Sum an expression of a cAdvisor series by container
+func byContainer(expr string) string {
+	return "sum by (namespace, pod, container) (" + expr + ")"
+}
+
+func cpuQuantity(cores float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Round(cores*1000)), resource.DecimalSI)
+}
+
+func memQuantity(bytes float64) resource.Quantity {
+	return *resource.NewQuantity(int64(bytes), resource.BinarySI)
+}
+
+func containerKey(labels map[string]string) string {
+	return labels["namespace"] + "/" + labels["pod"] + "/" + labels["container"]
+}
+
+// Order series by a key of their labels, Prometheus returns them in no order
+func sortBy(result []series, key func(labels map[string]string) string) {
+	sort.Slice(result, func(i, j int) bool { return key(result[i].labels) < key(result[j].labels) })
+}
+
+// Check that Prometheus answers and has the cAdvisor series, false if it has none
+func (c *Client) Available() (bool, error) {
+	result, err := c.query(context.TODO(), "count(container_cpu_usage_seconds_total)")
+	if err != nil {
+		return false, err
+	}
+	return len(result) != 0, nil
+}
+
+// Get the current usage of every container like metrics-server
+func (c *Client) PodMetrics(ctx context.Context, namespace string) (*v1beta1.PodMetricsList, error) {
+	sel := containerSelector(namespace)
+	cpu, err := c.query(ctx, byContainer("rate(container_cpu_usage_seconds_total{"+sel+"}["+promDuration(rateRange)+"])"))
+	if err != nil {
+		return nil, err
+	}
+	mem, err := c.query(ctx, byContainer("container_memory_working_set_bytes{"+sel+"}"))
+	if err != nil {
+		return nil, err
+	}
+
+	memory := make(map[string]float64, len(mem))
+	for _, s := range mem {
+		memory[containerKey(s.labels)] = s.value
+	}
+	sortBy(cpu, containerKey)
+	list := &v1beta1.PodMetricsList{}
+	pods := make(map[string]int)
+	for _, s := range cpu {
+		podKey := s.labels["namespace"] + "/" + s.labels["pod"]
+		i, ok := pods[podKey]
+		if !ok {
+			i = len(list.Items)
+			pods[podKey] = i
+			list.Items = append(list.Items, v1beta1.PodMetrics{
+				ObjectMeta: metav1.ObjectMeta{Name: s.labels["pod"], Namespace: s.labels["namespace"]},
+				Timestamp:  metav1.NewTime(s.at),
+				Window:     metav1.Duration{Duration: rateRange},
+			})
+		}
+		list.Items[i].Containers = append(list.Items[i].Containers, v1beta1.ContainerMetrics{
+			Name: s.labels["container"],
+			Usage: v1.ResourceList{
+				v1.ResourceCPU:    cpuQuantity(s.value),
+				v1.ResourceMemory: memQuantity(memory[containerKey(s.labels)]),
+			},
+		})
+	}
+	return list, nil
+}
+
+// Get the current usage of every node from the series of its root cgroup
+func (c *Client) NodeMetrics(ctx context.Context) (*v1beta1.NodeMetricsList, error) {
+	cpu, err := c.query(ctx, "sum by (node) (rate(container_cpu_usage_seconds_total{"+nodeSelector+"}["+promDuration(rateRange)+"]))")
+	if err != nil {
+		return nil, err
+	}
+	mem, err := c.query(ctx, "sum by (node) (container_memory_working_set_bytes{"+nodeSelector+"})")
+	if err != nil {
+		return nil, err
+	}
+	memory := make(map[string]float64, len(mem))
+	for _, s := range mem {
+		memory[s.labels["node"]] = s.value
+	}
+
+	sortBy(cpu, func(labels map[string]string) string { return labels["node"] })
+	list := &v1beta1.NodeMetricsList{}
+	for _, s := range cpu {
+		if s.labels["node"] == "" {
+			return nil, errors.New("prometheus cAdvisor series have no node label, relabel the kubelet targets with it")
+		}
+		list.Items = append(list.Items, v1beta1.NodeMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: s.labels["node"]},
+			Timestamp:  metav1.NewTime(s.at),
+			Window:     metav1.Duration{Duration: rateRange},
+			Usage: v1.ResourceList{
+				v1.ResourceCPU:    cpuQuantity(s.value),
+				v1.ResourceMemory: memQuantity(memory[s.labels["node"]]),
+			},
+		})
+	}
+	return list, nil
+}
+
+/*
+Get the average and peak usage of every container over the window, and the
+percent of its cpu periods that were throttled. The cpu peak is the highest
+rate over rateRange, taken every peakStep
+*/
+func (c *Client) ContainerWindowUsage(ctx context.Context, namespace string, window time.Duration) ([]metrics.WindowUsage, error) {
+	sel := containerSelector(namespace)
+	w := promDuration(window)
+	queries := []string{
+		byContainer("rate(container_cpu_usage_seconds_total{" + sel + "}[" + w + "])"),
+		"max_over_time(" + byContainer("rate(container_cpu_usage_seconds_total{"+sel+"}["+promDuration(rateRange)+"])") + "[" + w + ":" + promDuration(peakStep) + "])",
+		byContainer("avg_over_time(container_memory_working_set_bytes{" + sel + "}[" + w + "])"),
+		byContainer("max_over_time(container_memory_working_set_bytes{" + sel + "}[" + w + "])"),
+		byContainer("increase(container_cpu_cfs_throttled_periods_total{"+sel+"}["+w+"])") +
+			" / " + byContainer("increase(container_cpu_cfs_periods_total{"+sel+"}["+w+"])"),
+	}
+	results := make([][]series, len(queries))
+	for i, q := range queries {
+		result, err := c.query(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+
+	sortBy(results[0], containerKey)
+	var usage []metrics.WindowUsage
+	index := make(map[string]int)
+	for _, s := range results[0] {
+		index[containerKey(s.labels)] = len(usage)
+		usage = append(usage, metrics.WindowUsage{
+			Namespace: s.labels["namespace"],
+			Pod:       s.labels["pod"],
+			Container: s.labels["container"],
+			AvgCPU:    cpuQuantity(s.value),
+		})
+	}
+	set := func(result []series, f func(u *metrics.WindowUsage, v float64)) {
+		for _, s := range result {
+			if i, ok := index[containerKey(s.labels)]; ok {
+				f(&usage[i], s.value)
+			}
+		}
+	}
+	set(results[1], func(u *metrics.WindowUsage, v float64) { u.PeakCPU = cpuQuantity(v) })
+	set(results[2], func(u *metrics.WindowUsage, v float64) { u.AvgMemory = memQuantity(v) })
+	set(results[3], func(u *metrics.WindowUsage, v float64) { u.PeakMemory = memQuantity(v) })
+	set(results[4], func(u *metrics.WindowUsage, v float64) { u.Throttled, u.HasThrottling = v*100, true })
+	return usage, nil
+}
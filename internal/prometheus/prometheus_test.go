@@ -0,0 +1,175 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/kerrors"
+	"github.com/sam0392in/kshow/internal/metrics"
+)
+
+const (
+	webCPU = `{"metric":{"namespace":"app","pod":"web-1","container":"web"},"value":[1704067200.5,"%s"]}`
+	dbCPU  = `{"metric":{"namespace":"app","pod":"db-0","container":"db"},"value":[1704067200.5,"%s"]}`
+)
+
+func vector(results ...string) string {
+	return `{"status":"success","data":{"resultType":"vector","result":[` + strings.Join(results, ",") + `]}}`
+}
+
+func sample(format, value string) string {
+	return strings.Replace(format, "%s", value, 1)
+}
+
+/*
+Stub Prometheus answering a query with the response of the first
+matching fragment, the queries it was sent are kept
+*/
+func stub(t *testing.T, responses [][2]string) (*Client, *[]string) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query().Get("query")
+		queries = append(queries, q)
+		for _, resp := range responses {
+			if strings.Contains(q, resp[0]) {
+				w.Write([]byte(resp[1]))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"unexpected query"}`))
+	}))
+	t.Cleanup(server.Close)
+	return New(server.URL + "/"), &queries
+}
+
+func TestPodMetrics(t *testing.T) {
+	c, queries := stub(t, [][2]string{
+		{"rate(container_cpu_usage_seconds_total", vector(sample(webCPU, "0.25"), sample(dbCPU, "1.5"))},
+		{"container_memory_working_set_bytes", vector(sample(webCPU, "67108864"), sample(dbCPU, "NaN"))},
+	})
+	list, err := c.PodMetrics(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Name != "db-0" || list.Items[1].Name != "web-1" {
+		t.Fatalf("PodMetrics() = %+v, want db-0 and web-1", list.Items)
+	}
+	web := list.Items[1].Containers[0]
+	if web.Name != "web" || web.Usage.Cpu().MilliValue() != 250 || web.Usage.Memory().Value() != 64<<20 {
+		t.Errorf("PodMetrics() web = %+v", web)
+	}
+	if db := list.Items[0].Containers[0]; db.Usage.Cpu().MilliValue() != 1500 || !db.Usage.Memory().IsZero() {
+		t.Errorf("PodMetrics() db = %+v, want no memory for a NaN sample", db)
+	}
+	if !list.Items[0].Timestamp.Time.Equal(time.Unix(1704067200, 5e8)) {
+		t.Errorf("PodMetrics() timestamp = %v", list.Items[0].Timestamp)
+	}
+	for _, q := range *queries {
+		if !strings.Contains(q, `namespace="app"`) || !strings.Contains(q, `container!="POD"`) {
+			t.Errorf("query %q does not select the containers of app", q)
+		}
+	}
+}
+
+func TestNodeMetrics(t *testing.T) {
+	node := `{"metric":{"node":"ip-10-0-1-1"},"value":[1704067200,"%s"]}`
+	c, _ := stub(t, [][2]string{
+		{"rate(container_cpu_usage_seconds_total", vector(sample(node, "1.2"))},
+		{"container_memory_working_set_bytes", vector(sample(node, "2147483648"))},
+	})
+	list, err := c.NodeMetrics(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "ip-10-0-1-1" || list.Items[0].Usage.Cpu().MilliValue() != 1200 || list.Items[0].Usage.Memory().Value() != 2<<30 {
+		t.Errorf("NodeMetrics() = %+v", list.Items)
+	}
+
+	c, _ = stub(t, [][2]string{{"container_", vector(`{"metric":{"instance":"10.0.1.1:10250"},"value":[1704067200,"1"]}`)}})
+	if _, err := c.NodeMetrics(context.Background()); err == nil {
+		t.Errorf("NodeMetrics() accepted series without a node label")
+	}
+}
+
+func TestContainerWindowUsage(t *testing.T) {
+	c, queries := stub(t, [][2]string{
+		{"max_over_time(sum by (namespace, pod, container) (rate(", vector(sample(webCPU, "0.9"), sample(dbCPU, "2"))},
+		{"container_cpu_cfs_throttled_periods_total", vector(sample(webCPU, "0.125"))},
+		{"rate(container_cpu_usage_seconds_total", vector(sample(webCPU, "0.3"), sample(dbCPU, "1"))},
+		{"avg_over_time(container_memory_working_set_bytes", vector(sample(webCPU, "104857600"), sample(dbCPU, "1073741824"))},
+		{"max_over_time(container_memory_working_set_bytes", vector(sample(webCPU, "209715200"), sample(dbCPU, "1073741824"))},
+	})
+	usage, err := c.ContainerWindowUsage(context.Background(), "", 6*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("ContainerWindowUsage() = %+v, want 2 containers", usage)
+	}
+	db, web := usage[0], usage[1]
+	if web.AvgCPU.MilliValue() != 300 || web.PeakCPU.MilliValue() != 900 || web.AvgMemory.Value() != 100<<20 || web.PeakMemory.Value() != 200<<20 {
+		t.Errorf("ContainerWindowUsage() web = %+v", web)
+	}
+	if !web.HasThrottling || web.Throttled != 12.5 {
+		t.Errorf("ContainerWindowUsage() web throttled = %v, %v, want 12.5%%", web.Throttled, web.HasThrottling)
+	}
+	if db.HasThrottling || db.PeakCPU.MilliValue() != 2000 {
+		t.Errorf("ContainerWindowUsage() db = %+v, want no throttling without a cpu limit", db)
+	}
+	for _, q := range *queries {
+		if strings.Contains(q, "namespace=") {
+			t.Errorf("query %q selects a namespace, want all", q)
+		}
+		if strings.Contains(q, "_over_time(") && !strings.Contains(q, "[21600s") {
+			t.Errorf("query %q is not over the 6h window", q)
+		}
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	c, _ := stub(t, [][2]string{{"count(container_cpu_usage_seconds_total)", vector(`{"metric":{},"value":[1704067200,"42"]}`)}})
+	if ok, err := c.Available(); !ok || err != nil {
+		t.Errorf("Available() = %v, %v, want true", ok, err)
+	}
+
+	c, _ = stub(t, [][2]string{{"count(", vector()}})
+	if ok, err := c.Available(); ok || err != nil {
+		t.Errorf("Available() without cAdvisor series = %v, %v, want false", ok, err)
+	}
+	defer metrics.SetSource(metrics.GetSource())
+	metrics.SetSource(c)
+	if err := metrics.Require(); !errors.Is(err, kerrors.ErrMetricsUnavailable) {
+		t.Errorf("Require() without cAdvisor series = %v, want ErrMetricsUnavailable", err)
+	}
+
+	c, _ = stub(t, nil)
+	if _, err := c.Available(); err == nil || !strings.Contains(err.Error(), "bad_data") {
+		t.Errorf("Available() error = %v, want the Prometheus error", err)
+	}
+}
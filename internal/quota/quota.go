@@ -0,0 +1,55 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"github.com/sam0392in/kshow/internal/cache"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+/*
+List ResourceQuotas,
+Returns list.items of ResourceQuotas
+*/
+func GetResourceQuotas(namespace *string) (*v1.ResourceQuotaList, error) {
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.ResourceQuotas(*namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ResourceQuotaList{Items: items}, nil
+}
+
+/*
+List LimitRanges,
+Returns list.items of LimitRanges
+*/
+func GetLimitRanges(namespace *string) (*v1.LimitRangeList, error) {
+	source, err := cache.GetSource()
+	if err != nil {
+		return nil, err
+	}
+	items, err := source.LimitRanges(*namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.LimitRangeList{Items: items}, nil
+}
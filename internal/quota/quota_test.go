@@ -0,0 +1,58 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	"github.com/sam0392in/kshow/internal/cache"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetQuotasAndLimitRanges(t *testing.T) {
+	cache.SetSource(cache.NewDirect(fake.NewSimpleClientset(
+		&v1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "compute"}},
+		&v1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "compute"}},
+		&v1.LimitRange{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "defaults"}},
+	)))
+	defer cache.SetSource(nil)
+
+	tests := []struct {
+		namespace           string
+		quotas, limitRanges int
+	}{
+		{"", 2, 1},
+		{"app", 1, 1},
+		{"db", 1, 0},
+	}
+	for _, tt := range tests {
+		quotas, err := GetResourceQuotas(&tt.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		limitRanges, err := GetLimitRanges(&tt.namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(quotas.Items) != tt.quotas || len(limitRanges.Items) != tt.limitRanges {
+			t.Errorf("namespace %q: %d quotas and %d LimitRanges, want %d and %d", tt.namespace, len(quotas.Items), len(limitRanges.Items), tt.quotas, tt.limitRanges)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sam0392in/kshow/internal/deployment"
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/packing"
+	"github.com/sam0392in/kshow/internal/pod"
+	"github.com/sam0392in/kshow/internal/style"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+)
+
+// Reasons a replica does not fit on a node
+const (
+	reasonUnschedulable   = "node unschedulable"
+	reasonTaint           = "untolerated taint"
+	reasonNodeAffinity    = "node affinity/selector mismatch"
+	reasonCPU             = "insufficient cpu"
+	reasonMemory          = "insufficient memory"
+	reasonPods            = "too many pods"
+	reasonAntiAffinity    = "pod anti-affinity"
+	reasonTopologySpread  = "topology spread constraint"
+	reasonMissingTopology = "node missing topology label"
+)
+
+// Placement is the number of new replicas scheduled on a node group
+type Placement struct {
+	NodeGroup, Tenancy, InstanceType string
+	Replicas                         int
+}
+
+// NewNodes is the estimate of nodes to add to a node group for the unscheduled replicas
+type NewNodes struct {
+	NodeGroup, Tenancy, InstanceType string
+	PodsPerNode, Nodes               int
+}
+
+// Result of simulating a scale-up
+type Result struct {
+	Current, Target, ToSchedule, Scheduled int
+	Placements                             []Placement
+	// nodes rejecting the first unscheduled replica, per reason
+	Reasons  map[string]int
+	NewNodes []NewNodes
+}
+
+type simNode struct {
+	node             v1.Node
+	freeCPU, freeMem float64
+	freePods         int
+	usage            packing.NodeUsage
+}
+
+// A pod taken into account for anti-affinity and topology spread
+type simPod struct {
+	namespace, nodeName string
+	labels              labels.Set
+}
+
+func newSimNodes(nodes []v1.Node, pods []v1.Pod) []*simNode {
+	usage := packing.GetNodeUsage(nodes, pods)
+	var sim []*simNode
+	for i, n := range nodes {
+		sim = append(sim, &simNode{
+			node:     n,
+			freeCPU:  usage[i].CPUAllocatable - usage[i].CPURequested,
+			freeMem:  usage[i].MemAllocatable - usage[i].MemRequested,
+			freePods: int(n.Status.Allocatable.Pods().Value()) - usage[i].Pods,
+			usage:    usage[i],
+		})
+	}
+	return sim
+}
+
+// Checks which do not depend on capacity or other pods
+func staticFit(p *v1.Pod, n v1.Node) string {
+	if _, untolerated := corev1helpers.FindMatchingUntoleratedTaint(n.Spec.Taints, p.Spec.Tolerations, func(t *v1.Taint) bool {
+		return t.Effect == v1.TaintEffectNoSchedule || t.Effect == v1.TaintEffectNoExecute
+	}); untolerated {
+		return reasonTaint
+	}
+	if match, _ := nodeaffinity.GetRequiredNodeAffinity(p).Match(&n); !match {
+		return reasonNodeAffinity
+	}
+	return ""
+}
+
+func termNamespaces(term v1.PodAffinityTerm, namespace string) map[string]bool {
+	namespaces := map[string]bool{}
+	for _, ns := range term.Namespaces {
+		namespaces[ns] = true
+	}
+	if len(namespaces) == 0 {
+		namespaces[namespace] = true
+	}
+	return namespaces
+}
+
+// Returns true if a pod matching a required anti-affinity term already runs in the node's topology domain
+func violatesAntiAffinity(p *v1.Pod, n v1.Node, nodes map[string]v1.Node, pods []simPod) bool {
+	if p.Spec.Affinity == nil || p.Spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range p.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		domain, ok := n.Labels[term.TopologyKey]
+		if !ok {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			continue
+		}
+		namespaces := termNamespaces(term, p.Namespace)
+		for _, sp := range pods {
+			if !namespaces[sp.namespace] || !selector.Matches(sp.labels) {
+				continue
+			}
+			if other, ok := nodes[sp.nodeName]; ok && other.Labels[term.TopologyKey] == domain {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the reason a DoNotSchedule topology spread constraint is violated, if any
+func violatesTopologySpread(p *v1.Pod, n v1.Node, eligible []v1.Node, nodes map[string]v1.Node, pods []simPod) string {
+	for _, c := range p.Spec.TopologySpreadConstraints {
+		if c.WhenUnsatisfiable != v1.DoNotSchedule {
+			continue
+		}
+		domain, ok := n.Labels[c.TopologyKey]
+		if !ok {
+			return reasonMissingTopology
+		}
+		selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+		if err != nil {
+			continue
+		}
+		counts := map[string]int{}
+		for _, e := range eligible {
+			if d, ok := e.Labels[c.TopologyKey]; ok {
+				counts[d] += 0
+			}
+		}
+		for _, sp := range pods {
+			if sp.namespace != p.Namespace || !selector.Matches(sp.labels) {
+				continue
+			}
+			if other, ok := nodes[sp.nodeName]; ok {
+				if d, ok := other.Labels[c.TopologyKey]; ok {
+					if _, eligibleDomain := counts[d]; eligibleDomain {
+						counts[d]++
+					}
+				}
+			}
+		}
+		minCount := math.MaxInt32
+		for _, count := range counts {
+			if count < minCount {
+				minCount = count
+			}
+		}
+		if counts[domain]+1-minCount > int(c.MaxSkew) {
+			return reasonTopologySpread
+		}
+	}
+	return ""
+}
+
+// Get the reason a replica does not fit on a node, empty if it fits
+func fit(p *v1.Pod, n *simNode, reqCPU, reqMem float64, eligible []v1.Node, nodes map[string]v1.Node, pods []simPod) string {
+	if n.node.Spec.Unschedulable {
+		return reasonUnschedulable
+	}
+	if reason := staticFit(p, n.node); reason != "" {
+		return reason
+	}
+	switch {
+	case n.freeCPU < reqCPU:
+		return reasonCPU
+	case n.freeMem < reqMem:
+		return reasonMemory
+	case n.freePods < 1:
+		return reasonPods
+	case violatesAntiAffinity(p, n.node, nodes, pods):
+		return reasonAntiAffinity
+	}
+	return violatesTopologySpread(p, n.node, eligible, nodes, pods)
+}
+
+func nodeGroupKey(n v1.Node) (string, string, string) {
+	return node.GetNodeGroup(n), node.GetCapacityType(n), node.GetInstanceType(n)
+}
+
+/*
+Simulate scaling a deployment to the target replicas,
+new replicas are placed one by one on the feasible node with the most free cpu,
+the way the default scheduler spreads pods. Preemption is not simulated
+*/
+func Simulate(d appsv1.Deployment, target int, nodes []v1.Node, pods []v1.Pod) Result {
+	template := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: d.Namespace, Labels: d.Spec.Template.Labels},
+		Spec:       d.Spec.Template.Spec,
+	}
+	requests := pod.GetPodRequests(template.Spec)
+	reqCPU := requests.Cpu().AsApproximateFloat64()
+	reqMem := requests.Memory().AsApproximateFloat64()
+
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		selector = labels.Nothing()
+	}
+
+	nodesByName := make(map[string]v1.Node)
+	for _, n := range nodes {
+		nodesByName[n.Name] = n
+	}
+
+	var (
+		simPods  []simPod
+		eligible []v1.Node
+	)
+	current := 0
+	for _, p := range pods {
+		if p.Spec.NodeName == "" || p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		simPods = append(simPods, simPod{namespace: p.Namespace, nodeName: p.Spec.NodeName, labels: labels.Set(p.Labels)})
+		if p.Namespace == d.Namespace && selector.Matches(labels.Set(p.Labels)) {
+			current++
+		}
+	}
+	for _, n := range nodes {
+		if staticFit(template, n) == "" {
+			eligible = append(eligible, n)
+		}
+	}
+
+	result := Result{Current: current, Target: target, Reasons: map[string]int{}}
+	if target > current {
+		result.ToSchedule = target - current
+	}
+
+	sim := newSimNodes(nodes, pods)
+	placements := map[[3]string]int{}
+	for i := 0; i < result.ToSchedule; i++ {
+		var best *simNode
+		reasons := map[string]int{}
+		for _, n := range sim {
+			if reason := fit(template, n, reqCPU, reqMem, eligible, nodesByName, simPods); reason != "" {
+				reasons[reason]++
+				continue
+			}
+			if best == nil || n.freeCPU > best.freeCPU {
+				best = n
+			}
+		}
+		if best == nil {
+			result.Reasons = reasons
+			break
+		}
+		best.freeCPU -= reqCPU
+		best.freeMem -= reqMem
+		best.freePods--
+		simPods = append(simPods, simPod{namespace: d.Namespace, nodeName: best.node.Name, labels: labels.Set(template.Labels)})
+		ng, tenancy, instanceType := nodeGroupKey(best.node)
+		placements[[3]string{ng, tenancy, instanceType}]++
+		result.Scheduled++
+	}
+
+	for key, count := range placements {
+		result.Placements = append(result.Placements, Placement{NodeGroup: key[0], Tenancy: key[1], InstanceType: key[2], Replicas: count})
+	}
+	sort.Slice(result.Placements, func(i, j int) bool {
+		return result.Placements[i].Replicas > result.Placements[j].Replicas
+	})
+
+	if remaining := result.ToSchedule - result.Scheduled; remaining > 0 {
+		result.NewNodes = estimateNewNodes(template, reqCPU, reqMem, remaining, sim)
+	}
+	return result
+}
+
+// Returns true if the template can run only one replica per node
+func oneReplicaPerNode(p *v1.Pod) bool {
+	if p.Spec.Affinity == nil || p.Spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range p.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err == nil && term.TopologyKey == "kubernetes.io/hostname" && selector.Matches(labels.Set(p.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Estimate new nodes per eligible node group,
+each node group is estimated as if all remaining replicas went there.
+A fresh node has the allocatable of an existing node of the group minus its daemonset pods
+*/
+func estimateNewNodes(p *v1.Pod, reqCPU, reqMem float64, remaining int, sim []*simNode) []NewNodes {
+	seen := map[string]bool{}
+	var estimates []NewNodes
+	for _, n := range sim {
+		ng, tenancy, instanceType := nodeGroupKey(n.node)
+		key := ng + "/" + tenancy + "/" + instanceType
+		if seen[key] || staticFit(p, n.node) != "" {
+			continue
+		}
+		seen[key] = true
+
+		daemonPods := n.usage.Pods - len(n.usage.Movable)
+		perNode := int(n.node.Status.Allocatable.Pods().Value()) - daemonPods
+		if reqCPU > 0 {
+			if fit := int((n.usage.CPUAllocatable - n.usage.DaemonCPU) / reqCPU); fit < perNode {
+				perNode = fit
+			}
+		}
+		if reqMem > 0 {
+			if fit := int((n.usage.MemAllocatable - n.usage.DaemonMem) / reqMem); fit < perNode {
+				perNode = fit
+			}
+		}
+		if oneReplicaPerNode(p) && perNode > 1 {
+			perNode = 1
+		}
+		estimate := NewNodes{NodeGroup: ng, Tenancy: tenancy, InstanceType: instanceType, PodsPerNode: perNode}
+		if perNode > 0 {
+			estimate.Nodes = (remaining + perNode - 1) / perNode
+		}
+		estimates = append(estimates, estimate)
+	}
+	return estimates
+}
+
+// Print the result of scaling a deployment, target is deploy/<name> or <name>
+func PrintScale(namespace, target string, replicas int) error {
+	name := target
+	if i := strings.Index(target, "/"); i >= 0 {
+		switch target[:i] {
+		case "deploy", "deployment", "deployments":
+			name = target[i+1:]
+		default:
+			return errors.New("only deployments can be scaled: " + target)
+		}
+	}
+	d, err := deployment.FindDeployment(namespace, name)
+	if err != nil {
+		return err
+	}
+	nodes, err := node.ListNodes()
+	if err != nil {
+		return err
+	}
+	allNamespaces := ""
+	pods, err := pod.GetPods(&allNamespaces)
+	if err != nil {
+		return err
+	}
+
+	r := Simulate(d, replicas, nodes, pods.Items)
+
+	fmt.Println("Deployment " + d.Namespace + "/" + d.Name + ": current replicas " + strconv.Itoa(r.Current) + ", target " + strconv.Itoa(r.Target) + ", new replicas " + strconv.Itoa(r.ToSchedule))
+	fmt.Println("Schedulable: " + strconv.Itoa(r.Scheduled) + "/" + strconv.Itoa(r.ToSchedule))
+	if len(r.Placements) != 0 {
+		fmt.Println()
+		w := style.NewWriter()
+		fmt.Fprintln(w, "NODEGROUP\t\tTENANCY\t\tINSTANCE-TYPE\t\tREPLICAS")
+		for _, p := range r.Placements {
+			fmt.Fprintln(w, p.NodeGroup+"\t\t"+p.Tenancy+"\t\t"+p.InstanceType+"\t\t"+strconv.Itoa(p.Replicas))
+		}
+		w.Flush()
+	}
+
+	if r.Scheduled == r.ToSchedule {
+		return nil
+	}
+	fmt.Println("\nUnschedulable: " + strconv.Itoa(r.ToSchedule-r.Scheduled))
+	var reasons []string
+	for reason, count := range r.Reasons {
+		reasons = append(reasons, strconv.Itoa(count)+" "+reason)
+	}
+	sort.Strings(reasons)
+	fmt.Println("Nodes rejecting the next replica: " + strings.Join(reasons, ", "))
+
+	if len(r.NewNodes) == 0 {
+		fmt.Println("No node group matches the pod template's tolerations and node affinity")
+		return nil
+	}
+	fmt.Println("\nNew nodes needed, if all remaining replicas go to one node group:")
+	w := style.NewWriter()
+	fmt.Fprintln(w, "NODEGROUP\t\tTENANCY\t\tINSTANCE-TYPE\t\tPODS-PER-NODE\t\tNEW-NODES")
+	for _, n := range r.NewNodes {
+		nodesNeeded := strconv.Itoa(n.Nodes)
+		if n.PodsPerNode == 0 {
+			nodesNeeded = "does not fit"
+		}
+		fmt.Fprintln(w, n.NodeGroup+"\t\t"+n.Tenancy+"\t\t"+n.InstanceType+"\t\t"+strconv.Itoa(n.PodsPerNode)+"\t\t"+nodesNeeded)
+	}
+	w.Flush()
+	return nil
+}
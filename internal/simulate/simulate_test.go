@@ -0,0 +1,120 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulate
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name, nodeGroup, capacityType, zone string, taints ...v1.Taint) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			"kubernetes.io/hostname":           name,
+			"eks.amazonaws.com/nodegroup":      nodeGroup,
+			"eks.amazonaws.com/capacityType":   capacityType,
+			"node.kubernetes.io/instance-type": "m5.xlarge",
+			"topology.kubernetes.io/zone":      zone,
+		}},
+		Spec: v1.NodeSpec{Taints: taints},
+		Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("16Gi"),
+			v1.ResourcePods:   resource.MustParse("110"),
+		}},
+	}
+}
+
+func testDeployment() appsv1.Deployment {
+	labels := map[string]string{"app": "web"}
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("1"),
+					v1.ResourceMemory: resource.MustParse("1Gi"),
+				}}}}},
+			},
+		},
+	}
+}
+
+func TestSimulateCapacityAndTaints(t *testing.T) {
+	nodes := []v1.Node{
+		testNode("od-1", "ondemand", "ON_DEMAND", "a"),
+		testNode("spot-1", "spot", "SPOT", "b", v1.Taint{Key: "nature", Value: "spot", Effect: v1.TaintEffectNoSchedule}),
+	}
+	r := Simulate(testDeployment(), 6, nodes, nil)
+	if r.Scheduled != 4 || r.ToSchedule != 6 {
+		t.Fatalf("scheduled %d of %d, want 4 of 6", r.Scheduled, r.ToSchedule)
+	}
+	if r.Reasons[reasonCPU] != 1 || r.Reasons[reasonTaint] != 1 {
+		t.Errorf("reasons = %v, want one insufficient cpu and one taint", r.Reasons)
+	}
+	if len(r.NewNodes) != 1 || r.NewNodes[0].NodeGroup != "ondemand" || r.NewNodes[0].PodsPerNode != 4 || r.NewNodes[0].Nodes != 1 {
+		t.Errorf("new nodes = %+v, want 1 ondemand node fitting 4 pods", r.NewNodes)
+	}
+
+	d := testDeployment()
+	d.Spec.Template.Spec.Tolerations = []v1.Toleration{{Key: "nature", Operator: v1.TolerationOpEqual, Value: "spot", Effect: v1.TaintEffectNoSchedule}}
+	if r := Simulate(d, 8, nodes, nil); r.Scheduled != 8 {
+		t.Errorf("scheduled %d with toleration, want 8", r.Scheduled)
+	}
+}
+
+func TestSimulateAntiAffinityAndSpread(t *testing.T) {
+	nodes := []v1.Node{
+		testNode("a-1", "spot", "SPOT", "a"),
+		testNode("a-2", "spot", "SPOT", "a"),
+		testNode("b-1", "spot", "SPOT", "b"),
+	}
+
+	d := testDeployment()
+	d.Spec.Template.Spec.Affinity = &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+			LabelSelector: d.Spec.Selector,
+			TopologyKey:   "kubernetes.io/hostname",
+		}},
+	}}
+	r := Simulate(d, 5, nodes, nil)
+	if r.Scheduled != 3 || r.Reasons[reasonAntiAffinity] != 3 {
+		t.Errorf("scheduled %d reasons %v, want 3 scheduled and 3 anti-affinity", r.Scheduled, r.Reasons)
+	}
+	if len(r.NewNodes) != 1 || r.NewNodes[0].PodsPerNode != 1 || r.NewNodes[0].Nodes != 2 {
+		t.Errorf("new nodes = %+v, want 2 nodes with 1 pod each", r.NewNodes)
+	}
+
+	d = testDeployment()
+	d.Spec.Template.Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: v1.DoNotSchedule,
+		LabelSelector:     d.Spec.Selector,
+	}}
+	// zone b fits 4 replicas, so zone a can take at most 5
+	r = Simulate(d, 12, nodes, nil)
+	if r.Scheduled != 9 {
+		t.Errorf("scheduled %d with topology spread, want 9", r.Scheduled)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package style colors cells of the tabwriter tables. tabwriter counts ANSI
+codes as text and would misalign colored columns, so Paint brackets a cell
+with one-rune markers and Stdout replaces them by the codes after alignment.
+The two markers come out as two spaces of padding behind the colored text
+*/
+package style
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// Color of a cell
+type Color int
+
+const (
+	None Color = iota
+	Red
+	Green
+	Yellow
+)
+
+// Values of --color
+const (
+	Auto   = "auto"
+	Always = "always"
+	Never  = "never"
+)
+
+// Markers in the private use area, one opening marker per color
+const (
+	markOpen  = '\uE000'
+	markClose = '\uE0FF'
+)
+
+var codes = map[Color]string{
+	Red:    "\x1b[31m",
+	Green:  "\x1b[32m",
+	Yellow: "\x1b[33m",
+}
+
+const reset = "\x1b[0m"
+
+// Statuses of pods, containers and nodes by color, anything else is not colored
+var statusColors = map[string]Color{
+	"Running":           Green,
+	"Ready":             Green,
+	"Succeeded":         Green,
+	"Completed":         Green,
+	"Pending":           Yellow,
+	"ContainerCreating": Yellow,
+	"PodInitializing":   Yellow,
+	"Terminating":       Yellow,
+	"SchedulingGated":   Yellow,
+	"CrashLoopBackOff":  Red,
+	"Error":             Red,
+	"Failed":            Red,
+	"OOMKilled":         Red,
+	"ErrImagePull":      Red,
+	"ImagePullBackOff":  Red,
+	"Evicted":           Red,
+	"Unknown":           Red,
+	"NotReady":          Red,
+}
+
+var enabled bool
+
+/*
+Configure coloring from --color, auto colors only when stdout is a terminal
+and NO_COLOR is not set
+*/
+func Configure(mode string) error {
+	switch mode {
+	case Always:
+		enabled = true
+	case Never:
+		enabled = false
+	case Auto, "":
+		_, noColor := os.LookupEnv("NO_COLOR")
+		enabled = !noColor && isTerminal(os.Stdout)
+	default:
+		return errors.New("unknown color mode " + mode + ", use always, never or auto")
+	}
+	return nil
+}
+
+// Enabled is true if cells are colored
+func Enabled() bool {
+	return enabled
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Paint a cell in a color, the text is returned as is when coloring is off
+func Paint(c Color, text string) string {
+	if !enabled || c == None {
+		return text
+	}
+	return string(markOpen+rune(c)) + text + string(markClose)
+}
+
+// Get the color of a pod, container or node status, Init:<reason> is colored as the reason
+func StatusColor(status string) Color {
+	if c, ok := statusColors[status]; ok {
+		return c
+	}
+	if reason, ok := strings.CutPrefix(status, "Init:"); ok {
+		if c := statusColors[reason]; c == Red {
+			return Red
+		}
+		return Yellow
+	}
+	return None
+}
+
+// Paint a status in its color
+func Status(status string) string {
+	return Paint(StatusColor(status), status)
+}
+
+/*
+Get the color of a usage against its request and limit, red over limitPercent
+of the limit and yellow over requestPercent of the request. A zero request or
+limit is not compared
+*/
+func UsageColor(usage, request, limit int64, requestPercent, limitPercent int) Color {
+	switch {
+	case limit > 0 && usage*100 > limit*int64(limitPercent):
+		return Red
+	case request > 0 && usage*100 > request*int64(requestPercent):
+		return Yellow
+	}
+	return None
+}
+
+var replacer = func() *strings.Replacer {
+	var pairs []string
+	for c, code := range codes {
+		pairs = append(pairs, string(markOpen+rune(c)), code)
+	}
+	return strings.NewReplacer(append(pairs, string(markClose), reset+"  ")...)
+}()
+
+// Writer replacing the markers of Paint by ANSI codes
+type writer struct {
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	if !enabled {
+		return w.w.Write(p)
+	}
+	if _, err := io.WriteString(w.w, replacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Stdout prints painted cells in color
+var Stdout io.Writer = writer{os.Stdout}
+
+// Get a tabwriter of the kshow tables on Stdout
+func NewWriter() *tabwriter.Writer {
+	return tabwriter.NewWriter(Stdout, 1, 1, 1, ' ', 0)
+}
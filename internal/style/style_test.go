@@ -0,0 +1,115 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package style
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+func TestStatusColor(t *testing.T) {
+	tests := []struct {
+		status string
+		want   Color
+	}{
+		{"Running", Green},
+		{"Ready", Green},
+		{"Pending", Yellow},
+		{"CrashLoopBackOff", Red},
+		{"NotReady", Red},
+		{"Init:0/2", Yellow},
+		{"Init:CrashLoopBackOff", Red},
+		{"ExitCode:1", None},
+	}
+	for _, tt := range tests {
+		if got := StatusColor(tt.status); got != tt.want {
+			t.Errorf("StatusColor(%q) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestUsageColor(t *testing.T) {
+	tests := []struct {
+		name                  string
+		usage, request, limit int64
+		want                  Color
+	}{
+		{"under request", 50, 100, 200, None},
+		{"over request", 150, 100, 200, Yellow},
+		{"over limit percent", 190, 100, 200, Red},
+		{"no request or limit", 1000, 0, 0, None},
+		{"limit only", 95, 0, 100, Red},
+	}
+	for _, tt := range tests {
+		if got := UsageColor(tt.usage, tt.request, tt.limit, 100, 90); got != tt.want {
+			t.Errorf("%s: UsageColor() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer func() { enabled = false }()
+	if err := Configure(Always); err != nil || !Enabled() {
+		t.Errorf("Configure(always) = %v, enabled %v", err, Enabled())
+	}
+	t.Setenv("NO_COLOR", "1")
+	if err := Configure(Auto); err != nil || Enabled() {
+		t.Errorf("Configure(auto) with NO_COLOR = %v, enabled %v", err, Enabled())
+	}
+	if err := Configure("sometimes"); err == nil {
+		t.Errorf("Configure() accepted an unknown mode")
+	}
+}
+
+var ansi = regexp.MustCompile("\x1b\\[[0-9]+m")
+
+func TestPaintKeepsColumnsAligned(t *testing.T) {
+	defer func() { enabled = false }()
+
+	table := func() string {
+		var out bytes.Buffer
+		w := tabwriter.NewWriter(writer{&out}, 1, 1, 1, ' ', 0)
+		fmt.Fprintln(w, "POD\t\tSTATUS\t\tNODE")
+		fmt.Fprintln(w, "web-1\t\t"+Status("Running")+"\t\tnode-a")
+		fmt.Fprintln(w, "web-2\t\t"+Status("CrashLoopBackOff")+"\t\tnode-b")
+		fmt.Fprintln(w, "web-3\t\t"+Status("ExitCode:1")+"\t\tnode-c")
+		w.Flush()
+		return out.String()
+	}
+
+	enabled = true
+	colored := table()
+	if !strings.Contains(colored, codes[Green]+"Running"+reset) || !strings.Contains(colored, codes[Red]+"CrashLoopBackOff"+reset) {
+		t.Fatalf("statuses not colored:\n%q", colored)
+	}
+	lines := strings.Split(ansi.ReplaceAllString(colored, ""), "\n")
+	column := strings.Index(lines[0], "NODE")
+	for _, line := range lines[1:4] {
+		if strings.Index(line, "node-") != column {
+			t.Errorf("misaligned row %q, NODE starts at %d", line, column)
+		}
+	}
+
+	enabled = false
+	if plain := table(); strings.Contains(plain, "\x1b") || strings.ContainsRune(plain, markClose) {
+		t.Errorf("colored output with coloring off:\n%q", plain)
+	}
+}
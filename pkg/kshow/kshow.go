@@ -0,0 +1,331 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package kshow collects the views of the kshow cli as typed results,
+so they can be embedded in other Go tooling. Every function reads through the
+given clientsets and returns errors instead of printing
+*/
+package kshow
+
+import (
+	"context"
+	"time"
+
+	"github.com/sam0392in/kshow/internal/cache"
+	"github.com/sam0392in/kshow/internal/hpa"
+	"github.com/sam0392in/kshow/internal/kerrors"
+	"github.com/sam0392in/kshow/internal/metrics"
+	"github.com/sam0392in/kshow/internal/node"
+	"github.com/sam0392in/kshow/internal/pdb"
+	"github.com/sam0392in/kshow/internal/pod"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Returned, wrapped, by ListContainerUsage when the metrics API is not served, test it with errors.Is
+var ErrMetricsUnavailable = kerrors.ErrMetricsUnavailable
+
+// Options select what is collected
+type Options struct {
+	// Namespace to read, empty for all namespaces
+	Namespace string
+}
+
+// PodRow is a pod with its kubectl style status and the node it runs on
+type PodRow struct {
+	Name, Namespace string
+	// Status is the reason shown by kubectl get pods, e.g. Running or CrashLoopBackOff
+	Status                 string
+	Ready, Total, Restarts int
+	Created                time.Time
+	// Node is empty for unscheduled pods, Tenancy is the EKS capacity type of the node
+	Node, Tenancy string
+	// Object is the listed pod, for fields kshow does not derive
+	Object *v1.Pod
+}
+
+// HPASummary is the HorizontalPodAutoscaler scaling a deployment
+type HPASummary struct {
+	Min, Max, Current, Desired int32
+	// Metrics as current/target, e.g. cpu:45%/70%
+	Metrics string
+	// PinnedAtMax is true if the HPA can not scale up any further
+	PinnedAtMax bool
+}
+
+// DeploymentSummary is a deployment with its running pods and what protects and scales it
+type DeploymentSummary struct {
+	Name, Namespace string
+	Replicas        int32
+	// Running pods, and how many of them run on on-demand and spot nodes
+	Running, OnDemand, Spot int
+	// Names of the PodDisruptionBudgets covering the pods
+	PDBs []string
+	// HPA is nil if no HorizontalPodAutoscaler targets the deployment
+	HPA         *HPASummary
+	Tolerations []string
+	// Object is the listed deployment, for fields kshow does not derive
+	Object *appsv1.Deployment
+}
+
+// NodeSummary is a node with its EKS placement labels
+type NodeSummary struct {
+	Name, Status, Version                        string
+	Created                                      time.Time
+	NodeGroup, Tenancy, InstanceType, Arch, Zone string
+	AllocatableCPU, AllocatableMemory            resource.Quantity
+	// Object is the listed node, for fields kshow does not derive
+	Object *v1.Node
+}
+
+// ContainerUsage is the current usage of a container against its requests and limits
+type ContainerUsage struct {
+	Namespace, Pod, Container string
+	// Type is app, init, sidecar or ephemeral
+	Type string
+	// HasUsage is false when listed without the metrics API, CPU and Memory are then zero
+	HasUsage                                   bool
+	CPU, Memory                                resource.Quantity
+	RequestCPU, LimitCPU, RequestMem, LimitMem resource.Quantity
+}
+
+// List pods with their status and the node they run on
+func ListPods(clientset kubernetes.Interface, opts Options) ([]PodRow, error) {
+	source := cache.SourceFor(clientset)
+	pods, err := source.Pods(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := source.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	tenancy := node.GetNodeTenancy(nodes)
+
+	rows := make([]PodRow, 0, len(pods))
+	for i, p := range pods {
+		status := pod.GetPodStatus(p)
+		rows = append(rows, PodRow{
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			Status:    status.Reason,
+			Ready:     status.Ready,
+			Total:     status.Total,
+			Restarts:  status.Restarts,
+			Created:   p.CreationTimestamp.Time,
+			Node:      p.Spec.NodeName,
+			Tenancy:   tenancy[p.Spec.NodeName],
+			Object:    &pods[i],
+		})
+	}
+	return rows, nil
+}
+
+// List deployments with their pod distribution, PDBs and HPA
+func ListDeployments(clientset kubernetes.Interface, opts Options) ([]DeploymentSummary, error) {
+	source := cache.SourceFor(clientset)
+	deployments, err := source.Deployments(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	pdbs, err := source.PodDisruptionBudgets(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	hpas, err := source.HorizontalPodAutoscalers(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	// pods and nodes are listed once and looked up per deployment
+	pods, err := source.Pods(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := source.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	index := pod.NewPodIndex(pods)
+	tenancy := node.GetNodeTenancy(nodes)
+
+	summaries := make([]DeploymentSummary, 0, len(deployments))
+	for i, d := range deployments {
+		s := DeploymentSummary{Name: d.Name, Namespace: d.Namespace, Replicas: 1, Object: &deployments[i]}
+		if d.Spec.Replicas != nil {
+			s.Replicas = *d.Spec.Replicas
+		}
+		for _, p := range index.ForWorkload(d.Namespace, "Deployment/"+d.Name) {
+			if p.Status.Phase != v1.PodRunning {
+				continue
+			}
+			s.Running++
+			switch tenancy[p.Spec.NodeName] {
+			case node.OnDemand:
+				s.OnDemand++
+			case node.Spot:
+				s.Spot++
+			}
+		}
+		for _, p := range pdb.FindForPods(pdbs, d.Namespace, d.Spec.Template.Labels) {
+			s.PDBs = append(s.PDBs, p.Name)
+		}
+		if h := hpa.FindForTarget(hpas, "Deployment", d.Namespace, d.Name); h != nil {
+			s.HPA = &HPASummary{
+				Min:         hpa.MinReplicas(h),
+				Max:         h.Spec.MaxReplicas,
+				Current:     h.Status.CurrentReplicas,
+				Desired:     h.Status.DesiredReplicas,
+				Metrics:     hpa.FormatMetrics(h),
+				PinnedAtMax: hpa.PinnedAtMax(h),
+			}
+		}
+		for _, t := range d.Spec.Template.Spec.Tolerations {
+			s.Tolerations = append(s.Tolerations, t.Key+"-"+string(t.Operator)+"-"+t.Value+"-"+string(t.Effect))
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+// List nodes with their status and placement labels
+func ListNodes(clientset kubernetes.Interface) ([]NodeSummary, error) {
+	nodes, err := cache.SourceFor(clientset).Nodes()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]NodeSummary, 0, len(nodes))
+	for i, n := range nodes {
+		status := "NotReady"
+		for _, c := range n.Status.Conditions {
+			if c.Type == v1.NodeReady && c.Status == v1.ConditionTrue {
+				status = "Ready"
+			}
+		}
+		arch, ok := n.Labels["kubernetes.io/arch"]
+		if !ok {
+			arch = n.Labels["beta.kubernetes.io/arch"]
+		}
+		summaries = append(summaries, NodeSummary{
+			Name:              n.Name,
+			Status:            status,
+			Version:           n.Status.NodeInfo.KubeletVersion,
+			Created:           n.CreationTimestamp.Time,
+			NodeGroup:         node.GetNodeGroup(n),
+			Tenancy:           node.GetCapacityType(n),
+			InstanceType:      node.GetInstanceType(n),
+			Arch:              arch,
+			Zone:              node.GetZone(n),
+			AllocatableCPU:    n.Status.Allocatable[v1.ResourceCPU],
+			AllocatableMemory: n.Status.Allocatable[v1.ResourceMemory],
+			Object:            &nodes[i],
+		})
+	}
+	return summaries, nil
+}
+
+// Check with discovery that the metrics API is served, e.g. before passing a metrics clientset to ListContainerUsage
+func MetricsAvailable(clientset kubernetes.Interface) (bool, error) {
+	return metrics.HasMetricsAPI(clientset.Discovery())
+}
+
+// PodMetricsLister lists the usage of pods, e.g. from metrics-server or Prometheus
+type PodMetricsLister interface {
+	PodMetrics(ctx context.Context, namespace string) (*v1beta1.PodMetricsList, error)
+}
+
+/*
+List the current usage of every container from metrics-server,
+with the requests and limits of the matching container of any type.
+With a nil metricsClient the containers of all pods are listed without usage
+*/
+func ListContainerUsage(clientset kubernetes.Interface, metricsClient metricsv.Interface, opts Options) ([]ContainerUsage, error) {
+	if metricsClient == nil {
+		return ListContainerUsageFrom(clientset, nil, opts)
+	}
+	return ListContainerUsageFrom(clientset, metrics.MetricsServer{Client: metricsClient}, opts)
+}
+
+// List the usage of every container like ListContainerUsage, read from any lister of pod usage
+func ListContainerUsageFrom(clientset kubernetes.Interface, lister PodMetricsLister, opts Options) ([]ContainerUsage, error) {
+	pods, err := cache.SourceFor(clientset).Pods(opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if lister == nil {
+		return listContainerResources(pods), nil
+	}
+	podMetrics, err := lister.PodMetrics(context.TODO(), opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	index := pod.NewPodIndex(pods)
+
+	var usage []ContainerUsage
+	for _, m := range podMetrics.Items {
+		p := index.Get(m.Namespace, m.Name)
+		if p == nil {
+			continue
+		}
+		containers := pod.GetPodContainers(*p)
+		for _, c := range m.Containers {
+			u := ContainerUsage{
+				Namespace: m.Namespace,
+				Pod:       m.Name,
+				Container: c.Name,
+				HasUsage:  true,
+				CPU:       c.Usage[v1.ResourceCPU],
+				Memory:    c.Usage[v1.ResourceMemory],
+			}
+			for _, pc := range containers {
+				if pc.Name == c.Name {
+					r := pod.GetContainerResources(pc.Resources)
+					u.Type = pc.Type
+					u.RequestCPU, u.LimitCPU, u.RequestMem, u.LimitMem = r.RequestCPU, r.LimitCPU, r.RequestMem, r.LimitMem
+					break
+				}
+			}
+			usage = append(usage, u)
+		}
+	}
+	return usage, nil
+}
+
+// List the requests and limits of every container of the pods, without usage
+func listContainerResources(pods []v1.Pod) []ContainerUsage {
+	var usage []ContainerUsage
+	for _, p := range pods {
+		for _, c := range pod.GetPodContainers(p) {
+			r := pod.GetContainerResources(c.Resources)
+			usage = append(usage, ContainerUsage{
+				Namespace:  p.Namespace,
+				Pod:        p.Name,
+				Container:  c.Name,
+				Type:       c.Type,
+				RequestCPU: r.RequestCPU,
+				LimitCPU:   r.LimitCPU,
+				RequestMem: r.RequestMem,
+				LimitMem:   r.LimitMem,
+			})
+		}
+	}
+	return usage
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 Samarth Kanungo.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kshow
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	v1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// Fake cluster of deployments with running pods spread round robin over on-demand and spot nodes
+func fakeCluster(nodes, deployments, replicas int) []runtime.Object {
+	var objects []runtime.Object
+	for n := 0; n < nodes; n++ {
+		capacityType := "ON_DEMAND"
+		if n%2 == 1 {
+			capacityType = "SPOT"
+		}
+		objects = append(objects, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-" + strconv.Itoa(n),
+				Labels: map[string]string{"eks.amazonaws.com/capacityType": capacityType, "kubernetes.io/arch": "arm64"},
+			},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+		})
+	}
+	controller := true
+	for d := 0; d < deployments; d++ {
+		name, namespace := "app-"+strconv.Itoa(d), "ns-"+strconv.Itoa(d%20)
+		replicaCount := int32(replicas)
+		objects = append(objects, &v1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: v1.DeploymentSpec{
+				Replicas: &replicaCount,
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+			},
+		})
+		for r := 0; r < replicas; r++ {
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:       namespace,
+					Name:            name + "-5d8f7-" + strconv.Itoa(r),
+					Labels:          map[string]string{"app": name, "pod-template-hash": "5d8f7"},
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: name + "-5d8f7", Controller: &controller}},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "node-" + strconv.Itoa((d*replicas+r)%nodes),
+					Containers: []corev1.Container{{
+						Name:      "app",
+						Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}},
+					}},
+				},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning},
+			})
+		}
+	}
+	return objects
+}
+
+func TestListDeployments(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	objects := append(fakeCluster(4, 2, 3),
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-0", Name: "app-0"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app-0"}},
+			},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "app-1"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "app-1"},
+				MaxReplicas:    3,
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: 3, DesiredReplicas: 3},
+		},
+	)
+
+	deployments, err := ListDeployments(fake.NewSimpleClientset(objects...), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range deployments {
+		if deployments[i].Object == nil || deployments[i].Object.Name != deployments[i].Name {
+			t.Errorf("ListDeployments()[%d].Object = %v", i, deployments[i].Object)
+		}
+		deployments[i].Object = nil
+	}
+	// app-0 runs on node-0, node-1, node-2 and app-1 on node-3, node-0, node-1
+	want := []DeploymentSummary{
+		{Name: "app-0", Namespace: "ns-0", Replicas: 3, Running: 3, OnDemand: 2, Spot: 1, PDBs: []string{"app-0"}},
+		{Name: "app-1", Namespace: "ns-1", Replicas: 3, Running: 3, OnDemand: 1, Spot: 2,
+			HPA: &HPASummary{Min: 1, Max: 3, Current: 3, Desired: 3, Metrics: "-", PinnedAtMax: true}},
+	}
+	if !reflect.DeepEqual(deployments, want) {
+		t.Errorf("ListDeployments() = %+v, want %+v", deployments, want)
+	}
+}
+
+func TestListPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset(fakeCluster(2, 2, 1)...)
+	rows, err := ListPods(clientset, Options{Namespace: "ns-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("ListPods(ns-1) returned %d pods, want 1", len(rows))
+	}
+	if r := rows[0]; r.Name != "app-1-5d8f7-0" || r.Node != "node-1" || r.Tenancy != "SPOT" || r.Status != "Running" || r.Total != 1 {
+		t.Errorf("ListPods(ns-1) = %+v", r)
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	nodes, err := ListNodes(fake.NewSimpleClientset(fakeCluster(2, 0, 0)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Status != "Ready" || nodes[0].Tenancy != "ON_DEMAND" || nodes[1].Tenancy != "SPOT" || nodes[0].Arch != "arm64" {
+		t.Errorf("ListNodes() = %+v", nodes)
+	}
+}
+
+func TestListContainerUsage(t *testing.T) {
+	clientset := fake.NewSimpleClientset(fakeCluster(1, 1, 1)...)
+	metricsClient := metricsfake.NewSimpleClientset()
+	// the fake metrics clientset does not find PodMetrics added as objects
+	metricsClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-0", Name: "app-0-5d8f7-0"},
+			Containers: []metricsv1beta1.ContainerMetrics{{
+				Name:  "app",
+				Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("120m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+			}},
+		}}}, nil
+	})
+
+	usage, err := ListContainerUsage(clientset, metricsClient, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 {
+		t.Fatalf("ListContainerUsage() returned %d containers, want 1", len(usage))
+	}
+	u := usage[0]
+	if u.Type != "app" || u.CPU.MilliValue() != 120 || u.RequestCPU.MilliValue() != 250 || !u.LimitCPU.IsZero() {
+		t.Errorf("ListContainerUsage() = %+v", u)
+	}
+}
+
+func TestListContainerUsageWithoutMetrics(t *testing.T) {
+	clientset := fake.NewSimpleClientset(fakeCluster(1, 1, 2)...)
+	if ok, err := MetricsAvailable(clientset); err != nil || ok {
+		t.Fatalf("MetricsAvailable() = %v, %v, want false", ok, err)
+	}
+
+	usage, err := ListContainerUsage(clientset, nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("ListContainerUsage() returned %d containers, want 2", len(usage))
+	}
+	if u := usage[0]; u.HasUsage || u.RequestCPU.MilliValue() != 250 {
+		t.Errorf("ListContainerUsage() = %+v", u)
+	}
+}
+
+// 15000 pods of 500 deployments on 300 nodes, listed once and looked up per deployment
+func BenchmarkListDeployments(b *testing.B) {
+	clientset := fake.NewSimpleClientset(fakeCluster(300, 500, 30)...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ListDeployments(clientset, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}